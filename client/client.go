@@ -0,0 +1,305 @@
+// Copyright 2023 Honyrik.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package client is the Go client of the service. The same Client talks to
+// the gRPC or the REST listener, retries transient failures with backoff and
+// returns the failures as *Error.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"time"
+
+	pb "github.com/Honyrik/opa-go-service/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	defaultTimeout     = 30 * time.Second
+	defaultMaxAttempts = 3
+	defaultMinBackoff  = 100 * time.Millisecond
+	defaultMaxBackoff  = 2 * time.Second
+	defaultMaxConns    = 100
+	defaultMaxMsgSize  = 100 * 1024 * 1024
+)
+
+// transport sends the requests, the gRPC and REST transports implement it.
+type transport interface {
+	execute(ctx context.Context, in *pb.ApiRequest) (*pb.ApiResult, error)
+	compile(ctx context.Context, in *pb.CompileRequest) (*pb.CompileResult, error)
+	check(ctx context.Context, in *pb.CheckRequest) (*pb.CheckResult, error)
+	format(ctx context.Context, in *pb.FormatRequest) (*pb.FormatResult, error)
+	runTests(ctx context.Context, in *pb.RunTestsRequest) (*pb.RunTestsResult, error)
+	close() error
+}
+
+type Client struct {
+	transport   transport
+	timeout     time.Duration
+	maxAttempts int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+
+	maxConns    int
+	httpClient  *http.Client
+	dialOptions []grpc.DialOption
+}
+
+type Option func(*Client)
+
+// WithTimeout sets the deadline of a call whose context has none, zero
+// disables it. The default is 30s.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithRetry sets the attempts of a call and the bounds of the exponential
+// backoff between them. One attempt disables the retries. The default is 3
+// attempts from 100ms up to 2s.
+func WithRetry(maxAttempts int, minBackoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxAttempts = maxAttempts
+		c.minBackoff = minBackoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+// WithMaxConns sets the connections the REST transport keeps open to the
+// server. The default is 100.
+func WithMaxConns(maxConns int) Option {
+	return func(c *Client) {
+		c.maxConns = maxConns
+	}
+}
+
+// WithHTTPClient sets the HTTP client of the REST transport, WithMaxConns is
+// ignored.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithDialOptions adds options to the gRPC dial of NewGRPC.
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(c *Client) {
+		c.dialOptions = append(c.dialOptions, opts...)
+	}
+}
+
+func newClient(opts []Option) *Client {
+	c := &Client{
+		timeout:     defaultTimeout,
+		maxAttempts: defaultMaxAttempts,
+		minBackoff:  defaultMinBackoff,
+		maxBackoff:  defaultMaxBackoff,
+		maxConns:    defaultMaxConns,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.maxAttempts < 1 {
+		c.maxAttempts = 1
+	}
+	return c
+}
+
+// NewGRPC returns a client of the gRPC listener at target, such as
+// localhost:8000. The calls share one connection.
+func NewGRPC(target string, opts ...Option) (*Client, error) {
+	c := newClient(opts)
+	t, err := dialGRPC(target, c.dialOptions)
+	if err != nil {
+		return nil, err
+	}
+	c.transport = t
+	return c, nil
+}
+
+// NewGRPCConn returns a client of an established gRPC connection, such as an
+// in-process one. Close does not close the connection.
+func NewGRPCConn(conn grpc.ClientConnInterface, opts ...Option) *Client {
+	c := newClient(opts)
+	c.transport = &grpcTransport{client: pb.NewApiClient(conn)}
+	return c
+}
+
+// NewREST returns a client of the REST listener at baseURL, such as
+// http://localhost:8080.
+func NewREST(baseURL string, opts ...Option) *Client {
+	c := newClient(opts)
+	c.transport = newRESTTransport(baseURL, c.httpClient, c.maxConns)
+	return c
+}
+
+// Close releases the connections of the client.
+func (c *Client) Close() error {
+	return c.transport.close()
+}
+
+// call runs the attempts of fn within the deadline of the client.
+func (c *Client) call(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Deadline(); !ok && c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn(ctx)
+		if err == nil || attempt >= c.maxAttempts || !isRetryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(c.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the wait after the attempt, doubling from minBackoff up to
+// maxBackoff with up to half of it as jitter.
+func (c *Client) backoff(attempt int) time.Duration {
+	backoff := c.minBackoff
+	for i := 1; i < attempt && backoff < c.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > c.maxBackoff {
+		backoff = c.maxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// Execute evaluates the request. A result with isSuccess false is returned
+// along with an *Error of CodeAPI.
+func (c *Client) Execute(ctx context.Context, in *pb.ApiRequest) (*pb.ApiResult, error) {
+	var res *pb.ApiResult
+	err := c.call(ctx, func(ctx context.Context) error {
+		var err error
+		res, err = c.transport.execute(ctx, in)
+		if err != nil {
+			return transportError(err)
+		}
+		if !res.IsSuccess {
+			return apiError(res.Error)
+		}
+		return nil
+	})
+	return res, err
+}
+
+// Compile partially evaluates the request. A result with isSuccess false is
+// returned along with an *Error of CodeAPI.
+func (c *Client) Compile(ctx context.Context, in *pb.CompileRequest) (*pb.CompileResult, error) {
+	var res *pb.CompileResult
+	err := c.call(ctx, func(ctx context.Context) error {
+		var err error
+		res, err = c.transport.compile(ctx, in)
+		if err != nil {
+			return transportError(err)
+		}
+		if !res.IsSuccess {
+			return apiError(res.Error)
+		}
+		return nil
+	})
+	return res, err
+}
+
+// Check checks the packages of the request. Compilation errors are reported
+// in the result, the error is only set when the check could not run.
+func (c *Client) Check(ctx context.Context, in *pb.CheckRequest) (*pb.CheckResult, error) {
+	var res *pb.CheckResult
+	err := c.call(ctx, func(ctx context.Context) error {
+		var err error
+		res, err = c.transport.check(ctx, in)
+		if err != nil {
+			return transportError(err)
+		}
+		if res.Error != "" {
+			return apiError(res.Error)
+		}
+		return nil
+	})
+	return res, err
+}
+
+// Format formats the packages of the request. Packages that fail to parse are
+// reported in the result, the error is only set when the format could not run.
+func (c *Client) Format(ctx context.Context, in *pb.FormatRequest) (*pb.FormatResult, error) {
+	var res *pb.FormatResult
+	err := c.call(ctx, func(ctx context.Context) error {
+		var err error
+		res, err = c.transport.format(ctx, in)
+		if err != nil {
+			return transportError(err)
+		}
+		if res.Error != "" {
+			return apiError(res.Error)
+		}
+		return nil
+	})
+	return res, err
+}
+
+// RunTests runs the tests of the request. Failed tests are reported in the
+// result, the error is only set when the tests could not run.
+func (c *Client) RunTests(ctx context.Context, in *pb.RunTestsRequest) (*pb.RunTestsResult, error) {
+	var res *pb.RunTestsResult
+	err := c.call(ctx, func(ctx context.Context) error {
+		var err error
+		res, err = c.transport.runTests(ctx, in)
+		if err != nil {
+			return transportError(err)
+		}
+		if res.Error != "" {
+			return apiError(res.Error)
+		}
+		return nil
+	})
+	return res, err
+}
+
+// Evaluate executes the request and decodes the result into out. Unless the
+// request sets resultFormat or resultPath, it is sent with resultFormat
+// "first" so out receives the value of the query, null when undefined.
+func (c *Client) Evaluate(ctx context.Context, in *pb.ApiRequest, out interface{}) error {
+	if in.ResultFormat == "" && in.ResultPath == "" {
+		in = proto.Clone(in).(*pb.ApiRequest)
+		in.ResultFormat = "first"
+	}
+	res, err := c.Execute(ctx, in)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(res.Result), out); err != nil {
+		return &Error{Code: CodeDecode, Message: err.Error(), Err: err}
+	}
+	return nil
+}
+
+// Allow evaluates the request as a decision, true only when the query value
+// is the boolean true. Undefined and any other value is false.
+func (c *Client) Allow(ctx context.Context, in *pb.ApiRequest) (bool, error) {
+	var value interface{}
+	if err := c.Evaluate(ctx, in, &value); err != nil {
+		return false, err
+	}
+	allow, ok := value.(bool)
+	return ok && allow, nil
+}
@@ -0,0 +1,376 @@
+// Copyright 2023 Honyrik.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package client_test
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Honyrik/opa-go-service/client"
+	"github.com/Honyrik/opa-go-service/cmd"
+	pb "github.com/Honyrik/opa-go-service/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testPolicy = "package test\n\nallow { input.user == \"bob\" }"
+
+// fastRetry keeps the backoff of the retry tests short.
+var fastRetry = client.WithRetry(3, time.Millisecond, 2*time.Millisecond)
+
+// newGRPCClient returns a client of the gRPC server served in process over
+// bufconn, the interceptor runs before every call when set.
+func newGRPCClient(t *testing.T, interceptor grpc.UnaryServerInterceptor, opts ...client.Option) *client.Client {
+	t.Helper()
+
+	var serverOpts []grpc.ServerOption
+	if interceptor != nil {
+		serverOpts = append(serverOpts, grpc.UnaryInterceptor(interceptor))
+	}
+	s := cmd.NewGrpcServer(serverOpts...)
+	lis := bufconn.Listen(1024 * 1024)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("unable to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return client.NewGRPCConn(conn, opts...)
+}
+
+// newRESTServer serves the REST handler with httptest, the middleware wraps
+// the handler when set.
+func newRESTServer(t *testing.T, middleware func(http.Handler) http.Handler) *httptest.Server {
+	t.Helper()
+
+	var handler http.Handler = cmd.NewRestHandler()
+	if middleware != nil {
+		handler = middleware(handler)
+	}
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// newRESTClient returns a client of the REST handler served by httptest.
+func newRESTClient(t *testing.T, middleware func(http.Handler) http.Handler, opts ...client.Option) *client.Client {
+	t.Helper()
+
+	ts := newRESTServer(t, middleware)
+	c := client.NewREST(ts.URL, append([]client.Option{client.WithHTTPClient(ts.Client())}, opts...)...)
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// transports returns the clients of both transports.
+func transports(t *testing.T, opts ...client.Option) map[string]*client.Client {
+	return map[string]*client.Client{
+		"grpc": newGRPCClient(t, nil, opts...),
+		"rest": newRESTClient(t, nil, opts...),
+	}
+}
+
+func TestExecute(t *testing.T) {
+	for name, c := range transports(t) {
+		t.Run(name, func(t *testing.T) {
+			res, err := c.Execute(context.Background(), &pb.ApiRequest{
+				Query: "result = input",
+				Input: `{"test":1}`,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !res.IsSuccess || !strings.Contains(res.Result, `"test":1`) {
+				t.Fatalf("unexpected result: %v", res)
+			}
+		})
+	}
+}
+
+func TestExecuteAPIError(t *testing.T) {
+	for name, c := range transports(t) {
+		t.Run(name, func(t *testing.T) {
+			res, err := c.Execute(context.Background(), &pb.ApiRequest{Query: "result ="})
+
+			var e *client.Error
+			if !errors.As(err, &e) {
+				t.Fatalf("expected *client.Error but got %v", err)
+			}
+			if e.Code != client.CodeAPI || e.Op == "" {
+				t.Fatalf("unexpected error: %+v", e)
+			}
+			if res == nil || res.IsSuccess {
+				t.Fatalf("expected the failed result but got %v", res)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	for name, c := range transports(t) {
+		t.Run(name, func(t *testing.T) {
+			var value struct {
+				User string `json:"user"`
+			}
+			err := c.Evaluate(context.Background(), &pb.ApiRequest{
+				Query: "input",
+				Input: `{"user":"bob"}`,
+			}, &value)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if value.User != "bob" {
+				t.Fatalf("unexpected value: %v", value)
+			}
+		})
+	}
+}
+
+func TestAllow(t *testing.T) {
+	tests := []struct {
+		note     string
+		input    string
+		expected bool
+	}{
+		{note: "allowed", input: `{"user":"bob"}`, expected: true},
+		{note: "undefined", input: `{"user":"alice"}`, expected: false},
+	}
+
+	for name, c := range transports(t) {
+		for _, tc := range tests {
+			t.Run(name+"/"+tc.note, func(t *testing.T) {
+				allow, err := c.Allow(context.Background(), &pb.ApiRequest{
+					Query:    "data.test.allow",
+					Packages: []string{testPolicy},
+					Input:    tc.input,
+				})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if allow != tc.expected {
+					t.Fatalf("expected %v but got %v", tc.expected, allow)
+				}
+			})
+		}
+	}
+}
+
+// failFirst answers the first n requests with status and a JSON error body.
+func failFirst(n int32, code int, attempts *int32) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(attempts, 1) <= n {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(code)
+				w.Write([]byte(`{"message":"try again"}`))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestRESTRetry(t *testing.T) {
+	var attempts int32
+	c := newRESTClient(t, failFirst(2, http.StatusServiceUnavailable, &attempts), fastRetry)
+
+	allow, err := c.Allow(context.Background(), &pb.ApiRequest{
+		Query:    "data.test.allow",
+		Packages: []string{testPolicy},
+		Input:    `{"user":"bob"}`,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !allow || attempts != 3 {
+		t.Fatalf("expected allowed after 3 attempts but got %v after %d", allow, attempts)
+	}
+}
+
+func TestRESTRetryExhausted(t *testing.T) {
+	var attempts int32
+	c := newRESTClient(t, failFirst(10, http.StatusServiceUnavailable, &attempts), fastRetry)
+
+	_, err := c.Execute(context.Background(), &pb.ApiRequest{Query: "x = 1"})
+
+	var e *client.Error
+	if !errors.As(err, &e) {
+		t.Fatalf("expected *client.Error but got %v", err)
+	}
+	if e.Code != "http_503" || e.StatusCode != http.StatusServiceUnavailable || e.Message != "try again" {
+		t.Fatalf("unexpected error: %+v", e)
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts but got %d", attempts)
+	}
+}
+
+func TestRESTErrorNotRetried(t *testing.T) {
+	var attempts int32
+	c := newRESTClient(t, failFirst(10, http.StatusBadRequest, &attempts), fastRetry)
+
+	_, err := c.Execute(context.Background(), &pb.ApiRequest{Query: "x = 1"})
+
+	var e *client.Error
+	if !errors.As(err, &e) {
+		t.Fatalf("expected *client.Error but got %v", err)
+	}
+	if e.Code != "http_400" || e.StatusCode != http.StatusBadRequest || e.Message != "try again" {
+		t.Fatalf("unexpected error: %+v", e)
+	}
+	if attempts != 1 {
+		t.Fatalf("expected 1 attempt but got %d", attempts)
+	}
+}
+
+func TestRESTErrorBody(t *testing.T) {
+	ts := newRESTServer(t, nil)
+
+	// The routes do not exist under the base URL, the body is the JSON error
+	// of echo.
+	c := client.NewREST(ts.URL+"/missing", client.WithHTTPClient(ts.Client()), fastRetry)
+	_, err := c.Execute(context.Background(), &pb.ApiRequest{Query: "x = 1"})
+
+	var e *client.Error
+	if !errors.As(err, &e) {
+		t.Fatalf("expected *client.Error but got %v", err)
+	}
+	if e.Code != "http_404" || e.StatusCode != http.StatusNotFound || e.Message != "Not Found" {
+		t.Fatalf("unexpected error: %+v", e)
+	}
+}
+
+func TestGRPCRetry(t *testing.T) {
+	var attempts int32
+	interceptor := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if atomic.AddInt32(&attempts, 1) <= 2 {
+			return nil, status.Error(codes.Unavailable, "try again")
+		}
+		return handler(ctx, req)
+	}
+	c := newGRPCClient(t, interceptor, fastRetry)
+
+	res, err := c.Execute(context.Background(), &pb.ApiRequest{Query: "x = 1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.IsSuccess || attempts != 3 {
+		t.Fatalf("expected success after 3 attempts but got %v after %d", res, attempts)
+	}
+}
+
+func TestGRPCErrorNotRetried(t *testing.T) {
+	var attempts int32
+	interceptor := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		atomic.AddInt32(&attempts, 1)
+		return nil, status.Error(codes.InvalidArgument, "bad request")
+	}
+	c := newGRPCClient(t, interceptor, fastRetry)
+
+	_, err := c.Execute(context.Background(), &pb.ApiRequest{Query: "x = 1"})
+
+	var e *client.Error
+	if !errors.As(err, &e) {
+		t.Fatalf("expected *client.Error but got %v", err)
+	}
+	if e.Code != "grpc_InvalidArgument" || e.GRPCCode != codes.InvalidArgument || e.Message != "bad request" {
+		t.Fatalf("unexpected error: %+v", e)
+	}
+	if attempts != 1 {
+		t.Fatalf("expected 1 attempt but got %d", attempts)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	interceptor := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	c := newGRPCClient(t, interceptor, fastRetry, client.WithTimeout(10*time.Millisecond))
+
+	_, err := c.Execute(context.Background(), &pb.ApiRequest{Query: "x = 1"})
+
+	var e *client.Error
+	if !errors.As(err, &e) || e.Code != client.CodeTimeout {
+		t.Fatalf("expected a timeout but got %v", err)
+	}
+}
+
+// roundTripperFunc fails every request with its error.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestRESTTransportRetry(t *testing.T) {
+	tests := []struct {
+		note     string
+		err      error
+		attempts int32
+	}{
+		{note: "connection refused", err: &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, attempts: 3},
+		{note: "connection reset", err: &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, attempts: 3},
+		{note: "certificate", err: x509.UnknownAuthorityError{}, attempts: 1},
+		{note: "other", err: errors.New("unsupported protocol"), attempts: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			var attempts int32
+			httpClient := &http.Client{Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+				atomic.AddInt32(&attempts, 1)
+				return nil, tc.err
+			})}
+			c := client.NewREST("http://localhost:8080", client.WithHTTPClient(httpClient), fastRetry)
+
+			_, err := c.Execute(context.Background(), &pb.ApiRequest{Query: "x = 1"})
+
+			var e *client.Error
+			if !errors.As(err, &e) || e.Code != client.CodeTransport {
+				t.Fatalf("expected a transport error but got %v", err)
+			}
+			if attempts != tc.attempts {
+				t.Fatalf("expected %d attempts but got %d", tc.attempts, attempts)
+			}
+		})
+	}
+}
+
+func TestRESTInvalidURLNotRetried(t *testing.T) {
+	c := client.NewREST("http://[::1", client.WithRetry(3, time.Minute, time.Minute))
+
+	start := time.Now()
+	_, err := c.Execute(context.Background(), &pb.ApiRequest{Query: "x = 1"})
+
+	var e *client.Error
+	if !errors.As(err, &e) || e.Code != client.CodeTransport {
+		t.Fatalf("expected a transport error but got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected no retry but the call took %v", elapsed)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2023 Honyrik.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Codes of Error besides "grpc_<code>" for gRPC status errors and
+// "http_<status>" for unexpected HTTP statuses.
+const (
+	CodeAPI       = "api_error"
+	CodeTimeout   = "timeout"
+	CodeTransport = "transport"
+	CodeDecode    = "decode_error"
+)
+
+// Error is the failure of a call.
+type Error struct {
+	// Code classifies the error.
+	Code string
+	// Op is the failed step reported by the server, such as "Eval" for the
+	// message "Unable Eval: ..." or "prepare query" for "unable to prepare
+	// query: ...".
+	Op string
+	// Message is the message of the server or of the transport.
+	Message string
+	// StatusCode is the HTTP status of a REST error.
+	StatusCode int
+	// GRPCCode is the status code of a gRPC error.
+	GRPCCode codes.Code
+	// Err is the transport error, if any.
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// httpStatusError is an unexpected status of the REST listener.
+type httpStatusError struct {
+	code    int
+	message string
+}
+
+func (e *httpStatusError) Error() string {
+	if e.message != "" {
+		return e.message
+	}
+	return http.StatusText(e.code)
+}
+
+// apiError returns the error of a result with isSuccess false.
+func apiError(message string) *Error {
+	e := &Error{
+		Code:    CodeAPI,
+		Message: message,
+	}
+	if index := strings.Index(message, ": "); index > 0 && strings.HasPrefix(strings.ToLower(message), "unable ") {
+		e.Op = strings.TrimPrefix(message[len("unable "):index], "to ")
+	}
+	return e
+}
+
+// transportError classifies the error of the transport.
+func transportError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	e = &Error{
+		Code:    CodeTransport,
+		Message: err.Error(),
+		Err:     err,
+	}
+
+	var netErr net.Error
+	var httpErr *httpStatusError
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		e.Code = CodeTimeout
+	} else if errors.As(err, &httpErr) {
+		e.Code = fmt.Sprintf("http_%d", httpErr.code)
+		e.StatusCode = httpErr.code
+	} else if st, ok := status.FromError(err); ok {
+		e.GRPCCode = st.Code()
+		e.Message = st.Message()
+		if st.Code() == codes.DeadlineExceeded {
+			e.Code = CodeTimeout
+		} else {
+			e.Code = "grpc_" + st.Code().String()
+		}
+	}
+	return e
+}
+
+// isRetryable reports whether a call failing with err may succeed when sent
+// again: a refused or reset connection, a timeout within the deadline of the
+// call, an unavailable gRPC server and the HTTP statuses of an overloaded
+// server. Errors of the server and other transport errors, such as an invalid
+// URL or a TLS failure, are returned at once.
+func isRetryable(err error) bool {
+	var e *Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	switch e.Code {
+	case CodeTransport:
+		return isTransientNetError(e.Err)
+	case CodeTimeout:
+		return true
+	case CodeAPI, CodeDecode:
+		return false
+	}
+	switch e.GRPCCode {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isTransientNetError reports whether the connection was refused, reset or
+// closed by the server before the response.
+func isTransientNetError(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,152 @@
+// Copyright 2023 Honyrik.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	pb "github.com/Honyrik/opa-go-service/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+type grpcTransport struct {
+	client pb.ApiClient
+	conn   *grpc.ClientConn
+}
+
+func dialGRPC(target string, opts []grpc.DialOption) (*grpcTransport, error) {
+	opts = append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(defaultMaxMsgSize), grpc.MaxCallSendMsgSize(defaultMaxMsgSize)),
+	}, opts...)
+	conn, err := grpc.Dial(target, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &grpcTransport{
+		client: pb.NewApiClient(conn),
+		conn:   conn,
+	}, nil
+}
+
+func (t *grpcTransport) execute(ctx context.Context, in *pb.ApiRequest) (*pb.ApiResult, error) {
+	return t.client.Execute(ctx, in)
+}
+
+func (t *grpcTransport) compile(ctx context.Context, in *pb.CompileRequest) (*pb.CompileResult, error) {
+	return t.client.Compile(ctx, in)
+}
+
+func (t *grpcTransport) check(ctx context.Context, in *pb.CheckRequest) (*pb.CheckResult, error) {
+	return t.client.Check(ctx, in)
+}
+
+func (t *grpcTransport) format(ctx context.Context, in *pb.FormatRequest) (*pb.FormatResult, error) {
+	return t.client.Format(ctx, in)
+}
+
+func (t *grpcTransport) runTests(ctx context.Context, in *pb.RunTestsRequest) (*pb.RunTestsResult, error) {
+	return t.client.RunTests(ctx, in)
+}
+
+func (t *grpcTransport) close() error {
+	if t.conn == nil {
+		return nil
+	}
+	return t.conn.Close()
+}
+
+type restTransport struct {
+	baseURL string
+	client  *http.Client
+}
+
+func newRESTTransport(baseURL string, client *http.Client, maxConns int) *restTransport {
+	if client == nil {
+		client = &http.Client{
+			Transport: &http.Transport{
+				Proxy:               http.ProxyFromEnvironment,
+				MaxIdleConns:        maxConns,
+				MaxIdleConnsPerHost: maxConns,
+			},
+		}
+	}
+	return &restTransport{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  client,
+	}
+}
+
+// post sends in as JSON to the route and decodes the response into out.
+func (t *restTransport) post(ctx context.Context, route string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.baseURL+route, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bs, _ := io.ReadAll(resp.Body)
+		var echoErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(bs, &echoErr) != nil {
+			echoErr.Message = strings.TrimSpace(string(bs))
+		}
+		return &httpStatusError{code: resp.StatusCode, message: echoErr.Message}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return &Error{Code: CodeDecode, Message: err.Error(), Err: err}
+	}
+	return nil
+}
+
+func (t *restTransport) execute(ctx context.Context, in *pb.ApiRequest) (*pb.ApiResult, error) {
+	res := new(pb.ApiResult)
+	return res, t.post(ctx, "/execute", in, res)
+}
+
+func (t *restTransport) compile(ctx context.Context, in *pb.CompileRequest) (*pb.CompileResult, error) {
+	res := new(pb.CompileResult)
+	return res, t.post(ctx, "/compile", in, res)
+}
+
+func (t *restTransport) check(ctx context.Context, in *pb.CheckRequest) (*pb.CheckResult, error) {
+	res := new(pb.CheckResult)
+	return res, t.post(ctx, "/check", in, res)
+}
+
+func (t *restTransport) format(ctx context.Context, in *pb.FormatRequest) (*pb.FormatResult, error) {
+	res := new(pb.FormatResult)
+	return res, t.post(ctx, "/format", in, res)
+}
+
+func (t *restTransport) runTests(ctx context.Context, in *pb.RunTestsRequest) (*pb.RunTestsResult, error) {
+	res := new(pb.RunTestsResult)
+	return res, t.post(ctx, "/test", in, res)
+}
+
+func (t *restTransport) close() error {
+	t.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,241 @@
+// Copyright 2023 Honyrik.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	myUtil "github.com/Honyrik/opa-go-service/util"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/util"
+	"github.com/spf13/cobra"
+)
+
+const benchFormatTable = "table"
+
+type benchCommandParams struct {
+	eval      evalCommandParams
+	count     int
+	benchtime time.Duration
+	parallel  int
+	format    *util.EnumFlag
+}
+
+type benchResult struct {
+	Ops         int64   `json:"ops"`
+	Parallel    int     `json:"parallel"`
+	DurationNs  int64   `json:"duration_ns"`
+	OpsPerSec   float64 `json:"ops_per_sec"`
+	NsPerOp     int64   `json:"ns_per_op"`
+	AllocsPerOp int64   `json:"allocs_per_op"`
+	BytesPerOp  int64   `json:"bytes_per_op"`
+	P50Ns       int64   `json:"p50_ns"`
+	P90Ns       int64   `json:"p90_ns"`
+	P99Ns       int64   `json:"p99_ns"`
+}
+
+func init() {
+
+	params := benchCommandParams{
+		eval: evalCommandParams{
+			explain:     util.NewEnumFlag(myUtil.ExplainOff, myUtil.ExplainModes),
+			format:      util.NewEnumFlag(evalFormatJSON, evalFormats),
+			resultLang:  util.NewEnumFlag(myUtil.ResultPathJSONPath, myUtil.ResultPathLanguages),
+			inputFormat: util.NewEnumFlag("", myUtil.InputFormats),
+		},
+		format: util.NewEnumFlag(benchFormatTable, []string{benchFormatTable, evalFormatJSON}),
+	}
+
+	benchCommand := &cobra.Command{
+		Use:   "bench <query>",
+		Short: "Benchmark a Rego query",
+		Long: `Benchmark a Rego query and print the results.
+
+The query is prepared once and evaluated --count times, or for --benchtime
+when no count is given, split across --parallel goroutines. The report has
+the throughput, the allocations per evaluation and the latency percentiles.
+
+Examples
+--------
+
+    $ opa-go-service bench --data policy/ --input input.json 'data.authz.allow'
+
+    $ opa-go-service bench --count 10000 --parallel 4 --format json --data policy/ 'data.authz.allow'
+`,
+
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if params.count < 0 {
+				return errors.New("specify --count of zero or more")
+			}
+			if params.benchtime <= 0 {
+				return errors.New("specify a positive --benchtime")
+			}
+			if params.parallel < 1 {
+				return errors.New("specify --parallel of one or more")
+			}
+			return validateEvalParams(&params.eval, args)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			_, err := bench(args, params, os.Stdout)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	addDataFlag(benchCommand.Flags(), &params.eval.dataPaths)
+	addLoadFlags(benchCommand.Flags(), &params.eval)
+	addInputFlag(benchCommand.Flags(), &params.eval.inputPath)
+	addInputFormatFlag(benchCommand.Flags(), params.eval.inputFormat)
+	addQueryStdinFlag(benchCommand.Flags(), &params.eval.stdin)
+	addInputStdinFlag(benchCommand.Flags(), &params.eval.stdinInput)
+	addOutputFormatFlag(benchCommand.Flags(), params.format)
+	benchCommand.Flags().IntVarP(&params.count, "count", "c", 0, "set number of evaluations, zero runs for --benchtime")
+	benchCommand.Flags().DurationVarP(&params.benchtime, "benchtime", "t", time.Second, "set duration of the benchmark when --count is zero")
+	benchCommand.Flags().IntVarP(&params.parallel, "parallel", "p", 1, "set number of goroutines evaluating the query")
+
+	RootCommand.AddCommand(benchCommand)
+}
+
+func bench(args []string, params benchCommandParams, w io.Writer) (bool, error) {
+
+	ctx := context.Background()
+
+	pq, evalArgs, err := prepareEval(ctx, args, params.eval)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := runBench(ctx, pq, evalArgs, params)
+	if err != nil {
+		return false, err
+	}
+
+	if params.format.String() == evalFormatJSON {
+		return true, printJSON(w, res)
+	}
+	printBench(w, res)
+	return true, nil
+}
+
+// runBench evaluates the prepared query until the count or the duration of the
+// params is reached and measures every evaluation.
+func runBench(ctx context.Context, pq rego.PreparedEvalQuery, evalArgs []rego.EvalOption, params benchCommandParams) (*benchResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var ops int64
+	next := func() bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		if params.count > 0 {
+			return atomic.AddInt64(&ops, 1) <= int64(params.count)
+		}
+		return true
+	}
+
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var benchErr error
+	latencies := make([][]time.Duration, params.parallel)
+
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	if params.count == 0 {
+		time.AfterFunc(params.benchtime, cancel)
+	}
+
+	start := time.Now()
+	for index := 0; index < params.parallel; index++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			for next() {
+				t0 := time.Now()
+				_, err := pq.Eval(context.Background(), evalArgs...)
+				latencies[index] = append(latencies[index], time.Since(t0))
+				if err != nil {
+					errOnce.Do(func() {
+						benchErr = err
+						cancel()
+					})
+					return
+				}
+			}
+		}(index)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	runtime.ReadMemStats(&after)
+
+	if benchErr != nil {
+		return nil, benchErr
+	}
+
+	var all []time.Duration
+	for _, l := range latencies {
+		all = append(all, l...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+
+	n := int64(len(all))
+	if n == 0 {
+		return nil, errors.New("no evaluation completed")
+	}
+
+	return &benchResult{
+		Ops:         n,
+		Parallel:    params.parallel,
+		DurationNs:  elapsed.Nanoseconds(),
+		OpsPerSec:   float64(n) / elapsed.Seconds(),
+		NsPerOp:     elapsed.Nanoseconds() * int64(params.parallel) / n,
+		AllocsPerOp: int64(after.Mallocs-before.Mallocs) / n,
+		BytesPerOp:  int64(after.TotalAlloc-before.TotalAlloc) / n,
+		P50Ns:       percentile(all, 50).Nanoseconds(),
+		P90Ns:       percentile(all, 90).Nanoseconds(),
+		P99Ns:       percentile(all, 99).Nanoseconds(),
+	}, nil
+}
+
+// percentile returns the nearest-rank percentile p of the sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func printBench(w io.Writer, res *benchResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "samples\t%d\n", res.Ops)
+	fmt.Fprintf(tw, "parallel\t%d\n", res.Parallel)
+	fmt.Fprintf(tw, "duration\t%v\n", time.Duration(res.DurationNs))
+	fmt.Fprintf(tw, "ops/sec\t%.2f\n", res.OpsPerSec)
+	fmt.Fprintf(tw, "ns/op\t%d\n", res.NsPerOp)
+	fmt.Fprintf(tw, "allocs/op\t%d\n", res.AllocsPerOp)
+	fmt.Fprintf(tw, "B/op\t%d\n", res.BytesPerOp)
+	fmt.Fprintf(tw, "p50\t%v\n", time.Duration(res.P50Ns))
+	fmt.Fprintf(tw, "p90\t%v\n", time.Duration(res.P90Ns))
+	fmt.Fprintf(tw, "p99\t%v\n", time.Duration(res.P99Ns))
+	tw.Flush()
+}
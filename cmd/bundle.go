@@ -0,0 +1,71 @@
+// Copyright 2023 Honyrik.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/keys"
+	"github.com/open-policy-agent/opa/loader"
+	"github.com/spf13/pflag"
+)
+
+const (
+	defaultVerificationKeyID = "default"
+	defaultSigningAlg        = "RS256"
+)
+
+type bundleVerificationParams struct {
+	key          string
+	keyID        string
+	alg          string
+	scope        string
+	excludeFiles []string
+}
+
+func addBundleVerificationFlags(fs *pflag.FlagSet, params *bundleVerificationParams) {
+	fs.StringVarP(&params.key, "verification-key", "", "", "set the secret (HMAC) or path of the PEM file containing the public key (RSA and ECDSA) to verify the bundles")
+	fs.StringVarP(&params.keyID, "verification-key-id", "", defaultVerificationKeyID, "name assigned to the verification key used for bundle verification")
+	fs.StringVarP(&params.alg, "signing-alg", "", defaultSigningAlg, "name of the signing algorithm")
+	fs.StringVarP(&params.scope, "scope", "", "", "scope to use for bundle signature verification")
+	fs.StringSliceVarP(&params.excludeFiles, "exclude-files-verify", "", []string{}, "set file names to exclude during bundle verification")
+}
+
+// verificationConfig returns the configuration verifying the signatures of
+// the bundles, nil without a key.
+func (p bundleVerificationParams) verificationConfig() (*bundle.VerificationConfig, error) {
+	if p.key == "" {
+		return nil, nil
+	}
+	keyConfig, err := keys.NewKeyConfig(p.key, p.alg, p.scope)
+	if err != nil {
+		return nil, err
+	}
+	return bundle.NewVerificationConfig(map[string]*keys.Config{p.keyID: keyConfig}, p.keyID, p.scope, p.excludeFiles), nil
+}
+
+// loadBundles loads the bundle directories and .tar.gz files by path. The
+// signatures are verified when a verification key is set and skipped
+// otherwise, like the bundles of a server without signing keys.
+func loadBundles(paths []string, filter loader.Filter, params bundleVerificationParams) (map[string]*bundle.Bundle, error) {
+	config, err := params.verificationConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	bundles := map[string]*bundle.Bundle{}
+	for _, path := range paths {
+		b, err := loader.NewFileLoader().
+			WithFilter(filter).
+			WithBundleVerificationConfig(config).
+			WithSkipBundleVerification(config == nil).
+			WithProcessAnnotation(true).
+			AsBundle(path)
+		if err != nil {
+			return nil, err
+		}
+		bundles[path] = b
+	}
+	return bundles, nil
+}
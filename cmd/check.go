@@ -0,0 +1,249 @@
+// Copyright 2023 Honyrik.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	pb "github.com/Honyrik/opa-go-service/grpc"
+	"github.com/labstack/echo"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/util"
+	"github.com/spf13/cobra"
+)
+
+type checkCommandParams struct {
+	strict bool
+	format *util.EnumFlag
+}
+
+// checkModules parses and compiles the sources by file name and returns all
+// errors. Unless strict is set, the errors strict mode would report are
+// returned as warnings.
+func checkModules(sources map[string]string, strict bool) (ast.Errors, ast.Errors) {
+	var names []string
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs ast.Errors
+	modules := map[string]*ast.Module{}
+	for _, name := range names {
+		module, err := ast.ParseModuleWithOpts(name, sources[name], ast.ParserOptions{ProcessAnnotation: true})
+		if err != nil {
+			var astErrs ast.Errors
+			if errors.As(err, &astErrs) {
+				errs = append(errs, astErrs...)
+			} else {
+				errs = append(errs, ast.NewError(ast.ParseErr, nil, err.Error()))
+			}
+			continue
+		}
+		if module == nil {
+			errs = append(errs, ast.NewError(ast.ParseErr, &ast.Location{File: name}, "empty module"))
+			continue
+		}
+		modules[name] = module
+	}
+
+	if len(errs) > 0 {
+		return errs, nil
+	}
+
+	compiler := ast.NewCompiler().
+		SetErrorLimit(0).
+		WithEnablePrintStatements(true).
+		WithStrict(strict)
+	compiler.Compile(modules)
+	if compiler.Failed() || strict {
+		return compiler.Errors, nil
+	}
+
+	strictModules := map[string]*ast.Module{}
+	for name, module := range modules {
+		strictModules[name] = module.Copy()
+	}
+	strictCompiler := ast.NewCompiler().
+		SetErrorLimit(0).
+		WithEnablePrintStatements(true).
+		WithStrict(true)
+	strictCompiler.Compile(strictModules)
+
+	return nil, strictCompiler.Errors
+}
+
+func checkMessages(errs ast.Errors) []*pb.CheckMessage {
+	var res []*pb.CheckMessage
+	for _, err := range errs {
+		message := &pb.CheckMessage{
+			Code:    err.Code,
+			Message: err.Message,
+		}
+		if err.Location != nil {
+			message.File = err.Location.File
+			message.Row = int32(err.Location.Row)
+			message.Col = int32(err.Location.Col)
+		}
+		res = append(res, message)
+	}
+	return res
+}
+
+func CheckRego(ctx context.Context, in *pb.CheckRequest) (*pb.CheckResult, error) {
+	if len(in.Packages) == 0 {
+		return &pb.CheckResult{
+			IsSuccess: false,
+			Error:     "Need packages",
+		}, nil
+	}
+
+	sources := map[string]string{}
+	for index, module := range in.Packages {
+		sources[fmt.Sprintf("rego_%d.rego", index)] = module
+	}
+
+	errs, warnings := checkModules(sources, in.Strict)
+
+	return &pb.CheckResult{
+		IsSuccess: len(errs) == 0,
+		Errors:    checkMessages(errs),
+		Warnings:  checkMessages(warnings),
+	}, nil
+}
+
+func (s *server) Check(ctx context.Context, in *pb.CheckRequest) (*pb.CheckResult, error) {
+	return CheckRego(ctx, in)
+}
+
+func Check(c echo.Context) error {
+	data := new(pb.CheckRequest)
+	err := c.Bind(data)
+	if err != nil {
+		c.JSON(http.StatusOK, &pb.CheckResult{
+			IsSuccess: false,
+			Error:     fmt.Sprintf("Unable Post Data: %v", err),
+		})
+		return nil
+	}
+	res, err := CheckRego(c.Request().Context(), data)
+
+	if err != nil {
+		c.JSON(http.StatusOK, &pb.CheckResult{
+			IsSuccess: false,
+			Error:     fmt.Sprintf("Unable Check Rego: %v", err),
+		})
+		return nil
+	}
+
+	c.JSON(http.StatusOK, res)
+	return nil
+}
+
+// readRegoSources reads the .rego files found under the paths.
+func readRegoSources(paths []string) (map[string]string, error) {
+	sources := map[string]string{}
+	for _, root := range paths {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || !strings.HasSuffix(path, ".rego") {
+				return nil
+			}
+			bs, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			sources[path] = string(bs)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sources, nil
+}
+
+func init() {
+
+	params := checkCommandParams{
+		format: util.NewEnumFlag(evalFormatPretty, []string{evalFormatPretty, evalFormatJSON}),
+	}
+
+	checkCommand := &cobra.Command{
+		Use:   "check <path> [path [...]]",
+		Short: "Check Rego source files",
+		Long: `Check Rego source files for parse and compilation errors.
+
+The query is never evaluated. Without --strict the issues reported by
+strict mode are printed as warnings.
+
+Examples
+--------
+
+    $ opa-go-service check --strict policy/
+`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("specify at least one file")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			ok, err := check(args, params, os.Stdout)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			if !ok {
+				os.Exit(1)
+			}
+		},
+	}
+
+	checkCommand.Flags().BoolVarP(&params.strict, "strict", "S", false, "enable compiler strict mode")
+	addOutputFormatFlag(checkCommand.Flags(), params.format)
+	RootCommand.AddCommand(checkCommand)
+}
+
+func check(args []string, params checkCommandParams, w io.Writer) (bool, error) {
+	sources, err := readRegoSources(args)
+	if err != nil {
+		return false, err
+	}
+
+	errs, warnings := checkModules(sources, params.strict)
+
+	if params.format.String() == evalFormatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err := encoder.Encode(&pb.CheckResult{
+			IsSuccess: len(errs) == 0,
+			Errors:    checkMessages(errs),
+			Warnings:  checkMessages(warnings),
+		})
+		return len(errs) == 0, err
+	}
+
+	for _, e := range errs {
+		fmt.Fprintln(w, e)
+	}
+	for _, e := range warnings {
+		fmt.Fprintf(w, "warning: %v\n", e)
+	}
+
+	return len(errs) == 0, nil
+}
@@ -0,0 +1,141 @@
+// Copyright 2023 Honyrik.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	pb "github.com/Honyrik/opa-go-service/grpc"
+	myUtil "github.com/Honyrik/opa-go-service/util"
+	"github.com/labstack/echo"
+	"github.com/open-policy-agent/opa/format"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+func partialRego(ctx context.Context, in *pb.CompileRequest) (*rego.PartialQueries, error) {
+	if in.Query == "" {
+		return nil, fmt.Errorf("Need query")
+	}
+
+	if err := myUtil.ValidateInputFormat(in.InputFormat); err != nil {
+		return nil, err
+	}
+
+	regoArgs := []func(*rego.Rego){rego.Query(in.Query)}
+
+	policyArgs, err := policyRegoArgs(in.Data, in.Packages)
+	if err != nil {
+		return nil, err
+	}
+	regoArgs = append(regoArgs, policyArgs...)
+
+	if len(in.Unknowns) > 0 {
+		regoArgs = append(regoArgs, rego.Unknowns(in.Unknowns))
+	}
+
+	if len(in.DisableInlining) > 0 {
+		regoArgs = append(regoArgs, rego.DisableInlining(in.DisableInlining))
+	}
+
+	if in.Input != "" {
+		input, err := myUtil.ParseInput("input", []byte(in.Input), in.InputFormat)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse input: %v", err)
+		}
+		regoArgs = append(regoArgs, rego.Input(input))
+	}
+
+	return rego.New(regoArgs...).Partial(ctx)
+}
+
+func CompileRego(ctx context.Context, in *pb.CompileRequest) (*pb.CompileResult, error) {
+	pq, err := partialRego(ctx, in)
+	if err != nil {
+		return &pb.CompileResult{
+			IsSuccess: false,
+			Error:     fmt.Sprintf("Unable Partial Eval: %v", err),
+		}, nil
+	}
+
+	res := &pb.CompileResult{
+		IsSuccess: true,
+	}
+
+	for _, query := range pq.Queries {
+		res.Queries = append(res.Queries, query.String())
+	}
+
+	for _, module := range pq.Support {
+		bs, err := format.Ast(module)
+		if err != nil {
+			return &pb.CompileResult{
+				IsSuccess: false,
+				Error:     fmt.Sprintf("Unable Format Support: %v", err),
+			}, nil
+		}
+		res.Support = append(res.Support, string(bs))
+	}
+
+	resJson, resJsonErr := json.Marshal(pq)
+	if resJsonErr != nil {
+		return &pb.CompileResult{
+			IsSuccess: false,
+			Error:     fmt.Sprintf("Unable Json: %v", resJsonErr),
+		}, nil
+	}
+	res.Result = string(resJson[:])
+
+	if in.SqlDialect != "" {
+		where, args, sqlErr := myUtil.ResidualToSQL(pq, in.SqlDialect)
+		if sqlErr != nil {
+			return &pb.CompileResult{
+				IsSuccess: false,
+				Error:     fmt.Sprintf("Unable Translate SQL: %v", sqlErr),
+			}, nil
+		}
+		argsJson, argsJsonErr := json.Marshal(args)
+		if argsJsonErr != nil {
+			return &pb.CompileResult{
+				IsSuccess: false,
+				Error:     fmt.Sprintf("Unable Json: %v", argsJsonErr),
+			}, nil
+		}
+		res.Sql = where
+		res.SqlArgs = string(argsJson[:])
+	}
+
+	return res, nil
+}
+
+func (s *server) Compile(ctx context.Context, in *pb.CompileRequest) (*pb.CompileResult, error) {
+	return CompileRego(ctx, in)
+}
+
+func Compile(c echo.Context) error {
+	data := new(pb.CompileRequest)
+	err := c.Bind(data)
+	if err != nil {
+		c.JSON(http.StatusOK, &pb.CompileResult{
+			IsSuccess: false,
+			Error:     fmt.Sprintf("Unable Post Data: %v", err),
+		})
+		return nil
+	}
+	res, err := CompileRego(c.Request().Context(), data)
+
+	if err != nil {
+		c.JSON(http.StatusOK, &pb.CompileResult{
+			IsSuccess: false,
+			Error:     fmt.Sprintf("Unable Compile Rego: %v", err),
+		})
+		return nil
+	}
+
+	c.JSON(http.StatusOK, res)
+	return nil
+}
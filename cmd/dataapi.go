@@ -0,0 +1,620 @@
+// Copyright 2023 Honyrik.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	myUtil "github.com/Honyrik/opa-go-service/util"
+	"github.com/labstack/echo"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/metrics"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/topdown"
+	"github.com/open-policy-agent/opa/util"
+)
+
+// Error codes of the OPA REST API.
+const (
+	dataAPICodeInvalidParameter = "invalid_parameter"
+	dataAPICodeResourceNotFound = "resource_not_found"
+	dataAPICodeResourceConflict = "resource_conflict"
+	dataAPICodeInternal         = "internal_error"
+)
+
+type dataAPIError struct {
+	Code    string     `json:"code"`
+	Message string     `json:"message"`
+	Errors  ast.Errors `json:"errors,omitempty"`
+}
+
+type dataAPIDataResponse struct {
+	Result      *interface{}           `json:"result,omitempty"`
+	Metrics     map[string]interface{} `json:"metrics,omitempty"`
+	Explanation []myUtil.TraceEvent    `json:"explanation,omitempty"`
+}
+
+type dataAPIQueryResponse struct {
+	Result      []map[string]interface{} `json:"result,omitempty"`
+	Metrics     map[string]interface{}   `json:"metrics,omitempty"`
+	Explanation []myUtil.TraceEvent      `json:"explanation,omitempty"`
+}
+
+type dataAPIPolicy struct {
+	ID  string      `json:"id"`
+	Raw string      `json:"raw"`
+	AST *ast.Module `json:"ast"`
+}
+
+type dataAPIPolicyResponse struct {
+	Result *dataAPIPolicy `json:"result"`
+}
+
+type dataAPIPoliciesResponse struct {
+	Result []*dataAPIPolicy `json:"result"`
+}
+
+type dataAPIRequest struct {
+	Input *interface{} `json:"input"`
+}
+
+type dataAPIQueryRequest struct {
+	Query string       `json:"query"`
+	Input *interface{} `json:"input"`
+}
+
+type dataAPIPatch struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// dataAPI serves the OPA REST API (/v1/data, /v1/policies and /v1/query) from
+// a store holding the data and the policies. The policies are compiled when
+// they change and the queries of /v1/data and /v1/query are prepared once per
+// compilation, keeping the maxDataAPIPrepared most recently used.
+type dataAPI struct {
+	store storage.Store
+
+	mu       sync.RWMutex
+	compiler *ast.Compiler
+	modules  map[string]*ast.Module
+	prepared *myUtil.LRU
+}
+
+const maxDataAPIPrepared = 1000
+
+var serverDataAPI = newDataAPI(inmem.New())
+
+func newDataAPI(store storage.Store) *dataAPI {
+	return &dataAPI{
+		store:    store,
+		compiler: ast.NewCompiler(),
+		modules:  map[string]*ast.Module{},
+		prepared: myUtil.NewLRU(maxDataAPIPrepared),
+	}
+}
+
+func (d *dataAPI) register(mux *echo.Echo) {
+	mux.GET("/v1/data", d.getData)
+	mux.GET("/v1/data/*", d.getData)
+	mux.POST("/v1/data", d.postData)
+	mux.POST("/v1/data/*", d.postData)
+	mux.PUT("/v1/data", d.putData)
+	mux.PUT("/v1/data/*", d.putData)
+	mux.PATCH("/v1/data", d.patchData)
+	mux.PATCH("/v1/data/*", d.patchData)
+	mux.DELETE("/v1/data/*", d.deleteData)
+	mux.GET("/v1/policies", d.listPolicies)
+	mux.GET("/v1/policies/*", d.getPolicy)
+	mux.PUT("/v1/policies/*", d.putPolicy)
+	mux.DELETE("/v1/policies/*", d.deletePolicy)
+	mux.GET("/v1/query", d.getQuery)
+	mux.POST("/v1/query", d.postQuery)
+}
+
+func dataAPIErrorf(c echo.Context, status int, code string, format string, args ...interface{}) error {
+	return c.JSON(status, &dataAPIError{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// dataAPIStorageError writes the error of a store operation.
+func dataAPIStorageError(c echo.Context, err error) error {
+	if storage.IsNotFound(err) {
+		return dataAPIErrorf(c, http.StatusNotFound, dataAPICodeResourceNotFound, "%v", err)
+	} else if storage.IsWriteConflictError(err) {
+		return dataAPIErrorf(c, http.StatusConflict, dataAPICodeResourceConflict, "%v", err)
+	} else if storage.IsInvalidPatch(err) {
+		return dataAPIErrorf(c, http.StatusBadRequest, dataAPICodeInvalidParameter, "%v", err)
+	}
+	return dataAPIErrorf(c, http.StatusInternalServerError, dataAPICodeInternal, "%v", err)
+}
+
+// dataAPIPath returns the storage path of the wildcard of the route, its
+// segments may be escaped.
+func dataAPIPath(c echo.Context) (storage.Path, bool) {
+	return storage.ParsePathEscaped("/" + strings.Trim(c.Param("*"), "/"))
+}
+
+// dataRef returns the reference of the document at path, integer segments
+// are array indexes.
+func dataRef(path storage.Path) ast.Ref {
+	ref := ast.Ref{ast.DefaultRootDocument}
+	for _, segment := range path {
+		if index, err := strconv.Atoi(segment); err == nil {
+			ref = append(ref, ast.IntNumberTerm(index))
+		} else {
+			ref = append(ref, ast.StringTerm(segment))
+		}
+	}
+	return ref
+}
+
+// prepare returns the prepared query of the key, prepared with the current
+// compiler on the first call. The cache hits and the preparation are counted
+// in m like the queries of /execute.
+func (d *dataAPI) prepare(ctx context.Context, key string, query func(*rego.Rego), m metrics.Metrics) (rego.PreparedEvalQuery, error) {
+	if m != nil {
+		m.Timer("server_query_prepare").Start()
+		defer m.Timer("server_query_prepare").Stop()
+	}
+
+	d.mu.RLock()
+	cached, exist := d.prepared.Get(key)
+	compiler := d.compiler
+	d.mu.RUnlock()
+	if exist {
+		if m != nil {
+			m.Counter("server_query_cache_hit").Incr()
+		}
+		return cached.(rego.PreparedEvalQuery), nil
+	}
+	if m != nil {
+		m.Counter("server_query_cache_miss").Incr()
+	}
+
+	pq, err := rego.New(query, rego.Compiler(compiler), rego.Store(d.store)).PrepareForEval(ctx)
+	if err != nil {
+		return pq, err
+	}
+
+	d.mu.Lock()
+	if d.compiler == compiler {
+		d.prepared.Add(key, pq)
+	}
+	d.mu.Unlock()
+	return pq, nil
+}
+
+// dataAPIMetrics returns the metrics of the request, nil unless the metrics
+// or instrument parameter is true.
+func dataAPIMetrics(c echo.Context) metrics.Metrics {
+	if c.QueryParam("metrics") == "true" || c.QueryParam("instrument") == "true" {
+		return metrics.New()
+	}
+	return nil
+}
+
+// eval evaluates the prepared query with the options of the URL: metrics,
+// instrument and explain.
+func (d *dataAPI) eval(c echo.Context, pq rego.PreparedEvalQuery, input *interface{}, m metrics.Metrics) (rego.ResultSet, map[string]interface{}, []myUtil.TraceEvent, error) {
+	evalArgs := []rego.EvalOption{
+		rego.EvalRuleIndexing(true),
+		rego.EvalEarlyExit(true),
+	}
+	if input != nil {
+		evalArgs = append(evalArgs, rego.EvalInput(*input))
+	}
+
+	if m != nil {
+		evalArgs = append(evalArgs, rego.EvalMetrics(m), rego.EvalInstrument(c.QueryParam("instrument") == "true"))
+	}
+
+	explain := c.QueryParam("explain")
+	var buf *topdown.BufferTracer
+	if myUtil.IsExplain(explain) {
+		buf = topdown.NewBufferTracer()
+		evalArgs = append(evalArgs, rego.EvalQueryTracer(buf))
+	}
+
+	result, err := pq.Eval(c.Request().Context(), evalArgs...)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var all map[string]interface{}
+	if m != nil {
+		all = m.All()
+	}
+	var explanation []myUtil.TraceEvent
+	if buf != nil {
+		explanation = myUtil.NewTraceEvents(myUtil.FilterTrace(*buf, explain))
+	}
+	return result, all, explanation, nil
+}
+
+func (d *dataAPI) evalData(c echo.Context, input *interface{}) error {
+	if err := myUtil.ValidateExplain(c.QueryParam("explain")); err != nil {
+		return dataAPIErrorf(c, http.StatusBadRequest, dataAPICodeInvalidParameter, "%v", err)
+	}
+	path, ok := dataAPIPath(c)
+	if !ok {
+		return dataAPIErrorf(c, http.StatusBadRequest, dataAPICodeInvalidParameter, "invalid path %q", c.Param("*"))
+	}
+
+	m := dataAPIMetrics(c)
+	ref := dataRef(path)
+	pq, err := d.prepare(c.Request().Context(), "data:"+ref.String(), rego.ParsedQuery(ast.NewBody(ast.NewExpr(ast.NewTerm(ref)))), m)
+	if err != nil {
+		return dataAPIErrorf(c, http.StatusBadRequest, dataAPICodeInvalidParameter, "%v", err)
+	}
+
+	result, all, explanation, err := d.eval(c, pq, input, m)
+	if err != nil {
+		return dataAPIErrorf(c, http.StatusInternalServerError, dataAPICodeInternal, "%v", err)
+	}
+
+	res := &dataAPIDataResponse{
+		Metrics:     all,
+		Explanation: explanation,
+	}
+	if len(result) > 0 && len(result[0].Expressions) > 0 {
+		res.Result = &result[0].Expressions[0].Value
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// getData evaluates the document at the path with the input of the input
+// parameter, if any.
+func (d *dataAPI) getData(c echo.Context) error {
+	var input *interface{}
+	if s := c.QueryParam("input"); s != "" {
+		var value interface{}
+		if err := util.UnmarshalJSON([]byte(s), &value); err != nil {
+			return dataAPIErrorf(c, http.StatusBadRequest, dataAPICodeInvalidParameter, "unable to parse input: %v", err)
+		}
+		input = &value
+	}
+	return d.evalData(c, input)
+}
+
+// postData evaluates the document at the path with the input of the body,
+// {"input": ...}.
+func (d *dataAPI) postData(c echo.Context) error {
+	var req dataAPIRequest
+	if err := readDataAPIBody(c, &req); err != nil {
+		return dataAPIErrorf(c, http.StatusBadRequest, dataAPICodeInvalidParameter, "unable to parse body: %v", err)
+	}
+	return d.evalData(c, req.Input)
+}
+
+// readDataAPIBody decodes the JSON body into v, an empty body leaves v unset.
+func readDataAPIBody(c echo.Context, v interface{}) error {
+	bs, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return err
+	}
+	if len(strings.TrimSpace(string(bs))) == 0 {
+		return nil
+	}
+	return util.UnmarshalJSON(bs, v)
+}
+
+// putData creates or replaces the document at the path.
+func (d *dataAPI) putData(c echo.Context) error {
+	path, ok := dataAPIPath(c)
+	if !ok {
+		return dataAPIErrorf(c, http.StatusBadRequest, dataAPICodeInvalidParameter, "invalid path %q", c.Param("*"))
+	}
+	var value interface{}
+	if err := readDataAPIBody(c, &value); err != nil {
+		return dataAPIErrorf(c, http.StatusBadRequest, dataAPICodeInvalidParameter, "unable to parse body: %v", err)
+	}
+
+	ctx := c.Request().Context()
+	err := storage.Txn(ctx, d.store, storage.WriteParams, func(txn storage.Transaction) error {
+		return writeDocument(ctx, d.store, txn, path, value)
+	})
+	if err != nil {
+		return dataAPIStorageError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// writeDocument creates or replaces the document at path, and its missing
+// parents, without reading the document.
+func writeDocument(ctx context.Context, store storage.Store, txn storage.Transaction, path storage.Path, value interface{}) error {
+	if len(path) == 0 {
+		return store.Write(ctx, txn, storage.ReplaceOp, path, value)
+	}
+	if err := storage.MakeDir(ctx, store, txn, path[:len(path)-1]); err != nil {
+		return err
+	}
+	err := store.Write(ctx, txn, storage.ReplaceOp, path, value)
+	if storage.IsNotFound(err) {
+		return store.Write(ctx, txn, storage.AddOp, path, value)
+	}
+	return err
+}
+
+// patchData applies the JSON Patch of the body, the paths of the operations
+// are relative to the path.
+func (d *dataAPI) patchData(c echo.Context) error {
+	root, ok := dataAPIPath(c)
+	if !ok {
+		return dataAPIErrorf(c, http.StatusBadRequest, dataAPICodeInvalidParameter, "invalid path %q", c.Param("*"))
+	}
+	var patches []dataAPIPatch
+	if err := readDataAPIBody(c, &patches); err != nil {
+		return dataAPIErrorf(c, http.StatusBadRequest, dataAPICodeInvalidParameter, "unable to parse body: %v", err)
+	}
+
+	ops := map[string]storage.PatchOp{
+		"add":     storage.AddOp,
+		"remove":  storage.RemoveOp,
+		"replace": storage.ReplaceOp,
+	}
+
+	ctx := c.Request().Context()
+	err := storage.Txn(ctx, d.store, storage.WriteParams, func(txn storage.Transaction) error {
+		for _, patch := range patches {
+			op, exist := ops[patch.Op]
+			if !exist {
+				return fmt.Errorf("invalid patch operation %q", patch.Op)
+			}
+			path, ok := storage.ParsePathEscaped("/" + strings.Trim(patch.Path, "/"))
+			if !ok {
+				return fmt.Errorf("invalid patch path %q", patch.Path)
+			}
+			path = append(append(storage.Path{}, root...), path...)
+			if err := d.store.Write(ctx, txn, op, path, patch.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if _, ok := err.(*storage.Error); !ok {
+			return dataAPIErrorf(c, http.StatusBadRequest, dataAPICodeInvalidParameter, "%v", err)
+		}
+		return dataAPIStorageError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// deleteData removes the document at the path.
+func (d *dataAPI) deleteData(c echo.Context) error {
+	path, ok := dataAPIPath(c)
+	if !ok || len(path) == 0 {
+		return dataAPIErrorf(c, http.StatusBadRequest, dataAPICodeInvalidParameter, "invalid path %q", c.Param("*"))
+	}
+
+	ctx := c.Request().Context()
+	err := storage.Txn(ctx, d.store, storage.WriteParams, func(txn storage.Transaction) error {
+		return d.store.Write(ctx, txn, storage.RemoveOp, path, nil)
+	})
+	if err != nil {
+		return dataAPIStorageError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func dataAPIPolicyID(c echo.Context) string {
+	return strings.Trim(c.Param("*"), "/")
+}
+
+func (d *dataAPI) listPolicies(c echo.Context) error {
+	ctx := c.Request().Context()
+	res := &dataAPIPoliciesResponse{Result: []*dataAPIPolicy{}}
+
+	err := storage.Txn(ctx, d.store, storage.TransactionParams{}, func(txn storage.Transaction) error {
+		ids, err := d.store.ListPolicies(ctx, txn)
+		if err != nil {
+			return err
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			policy, err := d.readPolicy(ctx, txn, id)
+			if err != nil {
+				return err
+			}
+			res.Result = append(res.Result, policy)
+		}
+		return nil
+	})
+	if err != nil {
+		return dataAPIStorageError(c, err)
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+func (d *dataAPI) readPolicy(ctx context.Context, txn storage.Transaction, id string) (*dataAPIPolicy, error) {
+	bs, err := d.store.GetPolicy(ctx, txn, id)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.RLock()
+	module := d.modules[id]
+	d.mu.RUnlock()
+	return &dataAPIPolicy{
+		ID:  id,
+		Raw: string(bs),
+		AST: module,
+	}, nil
+}
+
+func (d *dataAPI) getPolicy(c echo.Context) error {
+	ctx := c.Request().Context()
+	var policy *dataAPIPolicy
+
+	err := storage.Txn(ctx, d.store, storage.TransactionParams{}, func(txn storage.Transaction) error {
+		var err error
+		policy, err = d.readPolicy(ctx, txn, dataAPIPolicyID(c))
+		return err
+	})
+	if err != nil {
+		return dataAPIStorageError(c, err)
+	}
+	return c.JSON(http.StatusOK, &dataAPIPolicyResponse{Result: policy})
+}
+
+// updatePolicies compiles the modules with the module of the id replaced, or
+// removed when module is nil, and stores the change with write. The compiler
+// is swapped when both succeed.
+func (d *dataAPI) updatePolicies(ctx context.Context, id string, module *ast.Module, write func(txn storage.Transaction) error) (ast.Errors, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	modules := make(map[string]*ast.Module, len(d.modules)+1)
+	for name, m := range d.modules {
+		if name != id {
+			modules[name] = m
+		}
+	}
+	if module != nil {
+		modules[id] = module
+	}
+
+	compiler := ast.NewCompiler().WithEnablePrintStatements(true)
+	compiler.Compile(modules)
+	if compiler.Failed() {
+		return compiler.Errors, nil
+	}
+
+	if err := storage.Txn(ctx, d.store, storage.WriteParams, write); err != nil {
+		return nil, err
+	}
+
+	d.compiler = compiler
+	d.modules = modules
+	d.prepared.Purge()
+	return nil, nil
+}
+
+// putPolicy creates or replaces the policy of the id with the Rego source of
+// the body.
+func (d *dataAPI) putPolicy(c echo.Context) error {
+	id := dataAPIPolicyID(c)
+	if id == "" {
+		return dataAPIErrorf(c, http.StatusBadRequest, dataAPICodeInvalidParameter, "missing policy id")
+	}
+	bs, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return dataAPIErrorf(c, http.StatusBadRequest, dataAPICodeInvalidParameter, "%v", err)
+	}
+
+	module, err := ast.ParseModuleWithOpts(id, string(bs), ast.ParserOptions{ProcessAnnotation: true})
+	if err != nil {
+		res := &dataAPIError{
+			Code:    dataAPICodeInvalidParameter,
+			Message: "error(s) occurred while parsing module(s)",
+		}
+		if errs, ok := err.(ast.Errors); ok {
+			res.Errors = errs
+		} else {
+			res.Message = err.Error()
+		}
+		return c.JSON(http.StatusBadRequest, res)
+	}
+	if module == nil {
+		return dataAPIErrorf(c, http.StatusBadRequest, dataAPICodeInvalidParameter, "empty module")
+	}
+
+	ctx := c.Request().Context()
+	errs, err := d.updatePolicies(ctx, id, module, func(txn storage.Transaction) error {
+		return d.store.UpsertPolicy(ctx, txn, id, bs)
+	})
+	if err != nil {
+		return dataAPIStorageError(c, err)
+	}
+	if len(errs) > 0 {
+		return c.JSON(http.StatusBadRequest, &dataAPIError{
+			Code:    dataAPICodeInvalidParameter,
+			Message: "error(s) occurred while compiling module(s)",
+			Errors:  errs,
+		})
+	}
+	return c.JSON(http.StatusOK, struct{}{})
+}
+
+// deletePolicy removes the policy of the id, unless the remaining policies
+// depend on it.
+func (d *dataAPI) deletePolicy(c echo.Context) error {
+	id := dataAPIPolicyID(c)
+
+	d.mu.RLock()
+	_, exist := d.modules[id]
+	d.mu.RUnlock()
+	if !exist {
+		return dataAPIErrorf(c, http.StatusNotFound, dataAPICodeResourceNotFound, "storage_not_found_error: policy id %q", id)
+	}
+
+	ctx := c.Request().Context()
+	errs, err := d.updatePolicies(ctx, id, nil, func(txn storage.Transaction) error {
+		return d.store.DeletePolicy(ctx, txn, id)
+	})
+	if err != nil {
+		return dataAPIStorageError(c, err)
+	}
+	if len(errs) > 0 {
+		return c.JSON(http.StatusBadRequest, &dataAPIError{
+			Code:    dataAPICodeInvalidParameter,
+			Message: "error(s) occurred while compiling module(s)",
+			Errors:  errs,
+		})
+	}
+	return c.JSON(http.StatusOK, struct{}{})
+}
+
+func (d *dataAPI) evalQuery(c echo.Context, query string, input *interface{}) error {
+	if err := myUtil.ValidateExplain(c.QueryParam("explain")); err != nil {
+		return dataAPIErrorf(c, http.StatusBadRequest, dataAPICodeInvalidParameter, "%v", err)
+	}
+	if query == "" {
+		return dataAPIErrorf(c, http.StatusBadRequest, dataAPICodeInvalidParameter, "missing query")
+	}
+
+	m := dataAPIMetrics(c)
+	pq, err := d.prepare(c.Request().Context(), "query:"+query, rego.Query(query), m)
+	if err != nil {
+		return dataAPIErrorf(c, http.StatusBadRequest, dataAPICodeInvalidParameter, "%v", err)
+	}
+
+	result, all, explanation, err := d.eval(c, pq, input, m)
+	if err != nil {
+		return dataAPIErrorf(c, http.StatusInternalServerError, dataAPICodeInternal, "%v", err)
+	}
+
+	return c.JSON(http.StatusOK, &dataAPIQueryResponse{
+		Result:      myUtil.ResultSetBindings(result),
+		Metrics:     all,
+		Explanation: explanation,
+	})
+}
+
+// getQuery evaluates the query of the q parameter.
+func (d *dataAPI) getQuery(c echo.Context) error {
+	return d.evalQuery(c, c.QueryParam("q"), nil)
+}
+
+// postQuery evaluates the query of the body, {"query": ..., "input": ...}.
+func (d *dataAPI) postQuery(c echo.Context) error {
+	var req dataAPIQueryRequest
+	if err := readDataAPIBody(c, &req); err != nil {
+		return dataAPIErrorf(c, http.StatusBadRequest, dataAPICodeInvalidParameter, "unable to parse body: %v", err)
+	}
+	return d.evalQuery(c, req.Query, req.Input)
+}
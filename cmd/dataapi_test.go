@@ -0,0 +1,69 @@
+// Copyright 2023 Honyrik.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo"
+	"github.com/open-policy-agent/opa/storage/inmem"
+)
+
+func TestDataAPIPreparedCache(t *testing.T) {
+	mux := echo.New()
+	newDataAPI(inmem.New()).register(mux)
+
+	do := func(method, target, body string) map[string]interface{} {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200 of %s %s but got %d: %s", method, target, rec.Code, rec.Body.String())
+		}
+		var res map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return res
+	}
+	counter := func(res map[string]interface{}, name string) interface{} {
+		t.Helper()
+		m, ok := res["metrics"].(map[string]interface{})
+		if !ok {
+			t.Fatalf("expected the metrics but got %v", res)
+		}
+		if _, ok := m["timer_server_query_prepare_ns"]; !ok {
+			t.Errorf("expected the timer_server_query_prepare_ns metric but got %v", m)
+		}
+		return m[name]
+	}
+
+	do(http.MethodPut, "/v1/policies/example", "package example\n\nallow = true\n")
+
+	for _, target := range []string{"/v1/query?q=x+%3D+data.example.allow&metrics=true", "/v1/data/example/allow?metrics=true"} {
+		if res := do(http.MethodGet, target, ""); counter(res, "counter_server_query_cache_miss") != float64(1) {
+			t.Fatalf("expected a cache miss of the first %s but got %v", target, res["metrics"])
+		}
+		if res := do(http.MethodGet, target, ""); counter(res, "counter_server_query_cache_hit") != float64(1) {
+			t.Fatalf("expected a cache hit of the second %s but got %v", target, res["metrics"])
+		}
+	}
+
+	// Updating the policies prepares the queries again.
+	do(http.MethodPut, "/v1/policies/example", "package example\n\nallow = false\n")
+	res := do(http.MethodGet, "/v1/query?q=x+%3D+data.example.allow&metrics=true", "")
+	if counter(res, "counter_server_query_cache_miss") != float64(1) {
+		t.Fatalf("expected a cache miss after the update but got %v", res["metrics"])
+	}
+	if result := res["result"].([]interface{}); len(result) != 1 || result[0].(map[string]interface{})["x"] != false {
+		t.Fatalf("expected the updated value but got %v", result)
+	}
+}
@@ -0,0 +1,356 @@
+// Copyright 2023 Honyrik.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/open-policy-agent/opa/metrics"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/util"
+)
+
+const (
+	defaultDataSourceInterval = time.Minute
+	defaultDataSourceTimeout  = 30 * time.Second
+
+	// dataSourceStaleIntervals is the number of intervals without a success
+	// after which a source is unhealthy.
+	dataSourceStaleIntervals = 3
+)
+
+// dataSourceConfig is a source of the data sources file, YAML or JSON:
+//
+//	sources:
+//	  - name: entitlements
+//	    url: https://entitlements.internal/export
+//	    path: /entitlements
+//	    interval: 30s
+//	    timeout: 10s
+//	    headers:
+//	      Authorization: Bearer ...
+type dataSourceConfig struct {
+	Name     string            `json:"name"`
+	URL      string            `json:"url"`
+	Path     string            `json:"path"`
+	Interval string            `json:"interval"`
+	Timeout  string            `json:"timeout"`
+	Headers  map[string]string `json:"headers"`
+}
+
+type dataSourcesConfig struct {
+	Sources []dataSourceConfig `json:"sources"`
+}
+
+// dataSourceStatus is the state of the polling of a source.
+type dataSourceStatus struct {
+	Name             string     `json:"name"`
+	URL              string     `json:"url"`
+	Path             string     `json:"path"`
+	ETag             string     `json:"etag,omitempty"`
+	LastRequest      *time.Time `json:"last_request,omitempty"`
+	LastSuccess      *time.Time `json:"last_success,omitempty"`
+	LastError        *time.Time `json:"last_error,omitempty"`
+	LastErrorMessage string     `json:"last_error_message,omitempty"`
+	Updates          int64      `json:"updates"`
+	NotModified      int64      `json:"not_modified"`
+	Errors           int64      `json:"errors"`
+	Healthy          bool       `json:"healthy"`
+}
+
+type dataSource struct {
+	config   dataSourceConfig
+	path     storage.Path
+	interval time.Duration
+	timeout  time.Duration
+
+	mu     sync.Mutex
+	status dataSourceStatus
+}
+
+// dataSources polls the sources and writes their documents into the store.
+// The polls are counted and timed in metrics.
+type dataSources struct {
+	store   storage.Store
+	client  *http.Client
+	sources []*dataSource
+	metrics metrics.Metrics
+}
+
+var serverDataSources *dataSources
+
+// readDataSourcesConfig reads the sources of the YAML or JSON file.
+func readDataSourcesConfig(file string) ([]dataSourceConfig, error) {
+	bs, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var config dataSourcesConfig
+	if err := util.Unmarshal(bs, &config); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return config.Sources, nil
+}
+
+func newDataSources(store storage.Store, client *http.Client, configs []dataSourceConfig) (*dataSources, error) {
+	s := &dataSources{
+		store:   store,
+		client:  client,
+		metrics: metrics.New(),
+	}
+	names := map[string]bool{}
+
+	for _, config := range configs {
+		if config.Name == "" || config.URL == "" {
+			return nil, fmt.Errorf("data source needs a name and a url")
+		}
+		if names[config.Name] {
+			return nil, fmt.Errorf("data source %q: duplicate name", config.Name)
+		}
+		names[config.Name] = true
+
+		path, ok := storage.ParsePathEscaped(config.Path)
+		if !ok || len(path) == 0 {
+			return nil, fmt.Errorf("data source %q: invalid path %q", config.Name, config.Path)
+		}
+
+		source := &dataSource{
+			config:   config,
+			path:     path,
+			interval: defaultDataSourceInterval,
+			timeout:  defaultDataSourceTimeout,
+			status: dataSourceStatus{
+				Name: config.Name,
+				URL:  config.URL,
+				Path: path.String(),
+			},
+		}
+		if config.Interval != "" {
+			interval, err := time.ParseDuration(config.Interval)
+			if err != nil || interval <= 0 {
+				return nil, fmt.Errorf("data source %q: invalid interval %q", config.Name, config.Interval)
+			}
+			source.interval = interval
+		}
+		if config.Timeout != "" {
+			timeout, err := time.ParseDuration(config.Timeout)
+			if err != nil || timeout <= 0 {
+				return nil, fmt.Errorf("data source %q: invalid timeout %q", config.Name, config.Timeout)
+			}
+			source.timeout = timeout
+		}
+		s.sources = append(s.sources, source)
+	}
+
+	return s, nil
+}
+
+// run polls every source on its interval until ctx is done, the first poll
+// is immediate.
+func (s *dataSources) run(ctx context.Context) {
+	for _, source := range s.sources {
+		go func(source *dataSource) {
+			ticker := time.NewTicker(source.interval)
+			defer ticker.Stop()
+			for {
+				if err := s.poll(ctx, source); err != nil {
+					log.Printf("data source %s: %v", source.config.Name, err)
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(source)
+	}
+}
+
+// poll requests the source with the ETag of the last response. A new
+// document replaces the one at the path of the source in one transaction.
+func (s *dataSources) poll(ctx context.Context, source *dataSource) error {
+	now := time.Now()
+	source.mu.Lock()
+	source.status.LastRequest = &now
+	etag := source.status.ETag
+	source.mu.Unlock()
+
+	timer := s.metrics.Timer(source.metricName("fetch"))
+	timer.Start()
+	etag, modified, err := s.fetch(ctx, source, etag)
+	timer.Stop()
+
+	source.mu.Lock()
+	now = time.Now()
+	if err != nil {
+		source.status.LastError = &now
+		source.status.LastErrorMessage = err.Error()
+		s.metrics.Counter(source.metricName("errors")).Incr()
+	} else {
+		source.status.LastSuccess = &now
+		if modified {
+			source.status.ETag = etag
+			s.metrics.Counter(source.metricName("updates")).Incr()
+		} else {
+			s.metrics.Counter(source.metricName("not_modified")).Incr()
+		}
+	}
+	source.mu.Unlock()
+
+	refreshReady()
+	return err
+}
+
+func (source *dataSource) metricName(name string) string {
+	return "data_source_" + source.config.Name + "_" + name
+}
+
+// healthy reports whether the source succeeded within the last
+// dataSourceStaleIntervals intervals, a source is unhealthy until its first
+// success. The lock of the source is held.
+func (source *dataSource) healthy() bool {
+	return source.status.LastSuccess != nil &&
+		time.Since(*source.status.LastSuccess) < dataSourceStaleIntervals*source.interval
+}
+
+func (s *dataSources) fetch(ctx context.Context, source *dataSource, etag string) (string, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, source.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source.config.URL, nil)
+	if err != nil {
+		return "", false, err
+	}
+	req.Header.Set("Accept", "application/json")
+	for name, value := range source.config.Headers {
+		req.Header.Set(name, value)
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return etag, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return "", false, fmt.Errorf("unexpected HTTP status %d", resp.StatusCode)
+	}
+
+	var value interface{}
+	if err := util.NewJSONDecoder(resp.Body).Decode(&value); err != nil {
+		return "", false, fmt.Errorf("unable to parse response: %v", err)
+	}
+
+	err = storage.Txn(ctx, s.store, storage.WriteParams, func(txn storage.Transaction) error {
+		return writeDocument(ctx, s.store, txn, source.path, value)
+	})
+	if err != nil {
+		return "", false, fmt.Errorf("unable to write %v: %v", source.path, err)
+	}
+
+	return resp.Header.Get("ETag"), true, nil
+}
+
+// statuses returns the status of every source by name, with the counters of
+// the metrics.
+func (s *dataSources) statuses() []dataSourceStatus {
+	res := []dataSourceStatus{}
+	for _, source := range s.sources {
+		source.mu.Lock()
+		status := source.status
+		status.Healthy = source.healthy()
+		source.mu.Unlock()
+
+		status.Updates = s.counter(source.metricName("updates"))
+		status.NotModified = s.counter(source.metricName("not_modified"))
+		status.Errors = s.counter(source.metricName("errors"))
+		res = append(res, status)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+func (s *dataSources) counter(name string) int64 {
+	value, _ := s.metrics.Counter(name).Value().(uint64)
+	return int64(value)
+}
+
+// healthy reports whether every source is healthy.
+func (s *dataSources) healthy() bool {
+	for _, source := range s.sources {
+		source.mu.Lock()
+		healthy := source.healthy()
+		source.mu.Unlock()
+		if !healthy {
+			return false
+		}
+	}
+	return true
+}
+
+// allMetrics returns the counters and the timers of the polls, with gauges
+// of the health and of the last success and error of every source in Unix
+// seconds.
+func (s *dataSources) allMetrics() map[string]interface{} {
+	res := s.metrics.All()
+	for _, source := range s.sources {
+		source.mu.Lock()
+		healthy := 0
+		if source.healthy() {
+			healthy = 1
+		}
+		res["gauge_"+source.metricName("healthy")] = healthy
+		if source.status.LastSuccess != nil {
+			res["gauge_"+source.metricName("last_success_seconds")] = source.status.LastSuccess.Unix()
+		}
+		if source.status.LastError != nil {
+			res["gauge_"+source.metricName("last_error_seconds")] = source.status.LastError.Unix()
+		}
+		source.mu.Unlock()
+	}
+	return res
+}
+
+// dataSourcesHealthy reports whether the sources of the server are healthy,
+// true without sources.
+func dataSourcesHealthy() bool {
+	return serverDataSources == nil || serverDataSources.healthy()
+}
+
+func DataSources(c echo.Context) error {
+	res := []dataSourceStatus{}
+	if serverDataSources != nil {
+		res = serverDataSources.statuses()
+	}
+	c.JSON(http.StatusOK, res)
+	return nil
+}
+
+// Metrics serves the metrics of the server in the format of the OPA metrics,
+// such as "counter_data_source_<name>_updates".
+func Metrics(c echo.Context) error {
+	res := map[string]interface{}{}
+	if serverDataSources != nil {
+		res = serverDataSources.allMetrics()
+	}
+	c.JSON(http.StatusOK, res)
+	return nil
+}
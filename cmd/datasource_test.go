@@ -0,0 +1,115 @@
+// Copyright 2023 Honyrik.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/util"
+)
+
+func TestDataSourcePoll(t *testing.T) {
+	var fail int32
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Header.Get("If-None-Match"))
+		if atomic.LoadInt32(&fail) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"alice": ["admin"]}`))
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	store := inmem.New()
+	s, err := newDataSources(store, ts.Client(), []dataSourceConfig{
+		{Name: "users", URL: ts.URL, Path: "/entitlements/users"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := s.sources[0]
+
+	if s.healthy() {
+		t.Fatal("expected the source to be unhealthy before the first poll")
+	}
+
+	// A 200 writes the document at the path of the source.
+	if err := s.poll(ctx, source); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	value, err := storage.ReadOne(ctx, store, storage.MustParsePath("/entitlements/users"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := util.MustUnmarshalJSON([]byte(`{"alice": ["admin"]}`)); util.Compare(value, expected) != 0 {
+		t.Fatalf("expected %v but got %v", expected, value)
+	}
+
+	// The next poll sends the ETag and the 304 keeps the document.
+	if err := s.poll(ctx, source); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Any other status is an error.
+	atomic.StoreInt32(&fail, 1)
+	if err := s.poll(ctx, source); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Fatalf("expected the error of the status but got %v", err)
+	}
+
+	if expected := []string{"", `"v1"`, `"v1"`}; !reflect.DeepEqual(requests, expected) {
+		t.Fatalf("expected If-None-Match %q but got %q", expected, requests)
+	}
+
+	statuses := s.statuses()
+	if len(statuses) != 1 {
+		t.Fatalf("expected 1 status but got %d", len(statuses))
+	}
+	status := statuses[0]
+	if status.Updates != 1 || status.NotModified != 1 || status.Errors != 1 {
+		t.Fatalf("expected 1 update, 1 not modified and 1 error but got %+v", status)
+	}
+	if status.ETag != `"v1"` || status.LastSuccess == nil || status.LastError == nil || !strings.Contains(status.LastErrorMessage, "500") {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+	if !status.Healthy || !s.healthy() {
+		t.Fatal("expected the source to stay healthy after a recent success")
+	}
+
+	all := s.allMetrics()
+	for name, expected := range map[string]interface{}{
+		"counter_data_source_users_updates":      uint64(1),
+		"counter_data_source_users_not_modified": uint64(1),
+		"counter_data_source_users_errors":       uint64(1),
+		"gauge_data_source_users_healthy":        1,
+	} {
+		if all[name] != expected {
+			t.Errorf("expected %s %v but got %v", name, expected, all[name])
+		}
+	}
+	for _, name := range []string{
+		"timer_data_source_users_fetch_ns",
+		"gauge_data_source_users_last_success_seconds",
+		"gauge_data_source_users_last_error_seconds",
+	} {
+		if _, ok := all[name]; !ok {
+			t.Errorf("expected the metric %s", name)
+		}
+	}
+}
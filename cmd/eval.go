@@ -6,20 +6,24 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	myUtil "github.com/Honyrik/opa-go-service/util"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/cover"
 	"github.com/open-policy-agent/opa/loader"
+	"github.com/open-policy-agent/opa/profiler"
 	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/topdown"
 	"github.com/open-policy-agent/opa/util"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"k8s.io/client-go/util/jsonpath"
 )
 
 const stringType = "string"
@@ -55,11 +59,29 @@ func (f *repeatedStringFlag) isFlagSet() bool {
 }
 
 type evalCommandParams struct {
-	dataPaths  repeatedStringFlag
-	inputPath  string
-	resultPath string
-	stdin      bool
-	stdinInput bool
+	dataPaths   repeatedStringFlag
+	bundlePaths repeatedStringFlag
+	ignore      []string
+	schemaPath  string
+	verify      bundleVerificationParams
+	inputPath   string
+	inputFormat *util.EnumFlag
+	resultPath  string
+	resultLang  *util.EnumFlag
+	stdin       bool
+	stdinInput  bool
+	explain     *util.EnumFlag
+	format      *util.EnumFlag
+
+	profile      bool
+	profileLimit int
+	profileSort  repeatedStringFlag
+
+	coverage bool
+	watch    bool
+
+	inputsJSONL string
+	parallel    int
 }
 
 func validateEvalParams(p *evalCommandParams, cmdArgs []string) error {
@@ -76,6 +98,21 @@ func validateEvalParams(p *evalCommandParams, cmdArgs []string) error {
 	if p.stdinInput && p.inputPath != "" {
 		return errors.New("specify --stdin-input or --input but not both")
 	}
+	if p.resultPath != "" && p.format.String() != evalFormatJSON {
+		return errors.New("specify --resultPath or --format but not both")
+	}
+	if err := myUtil.ValidateProfileSort(p.profileSort.v); err != nil {
+		return err
+	}
+	if p.verify.key != "" && len(p.bundlePaths.v) == 0 {
+		return errors.New("specify --bundle to verify with --verification-key")
+	}
+	if p.watch && (p.stdin || p.stdinInput) {
+		return errors.New("specify --watch or read from stdin but not both")
+	}
+	if p.watch && len(p.dataPaths.v) == 0 && len(p.bundlePaths.v) == 0 && p.inputPath == "" {
+		return errors.New("specify --data, --bundle or --input to watch")
+	}
 
 	return nil
 }
@@ -95,7 +132,12 @@ func (f loaderFilter) Apply(abspath string, info os.FileInfo, depth int) bool {
 
 func init() {
 
-	params := evalCommandParams{}
+	params := evalCommandParams{
+		explain:     util.NewEnumFlag(myUtil.ExplainOff, myUtil.ExplainModes),
+		format:      util.NewEnumFlag(evalFormatJSON, evalFormats),
+		resultLang:  util.NewEnumFlag(myUtil.ResultPathJSONPath, myUtil.ResultPathLanguages),
+		inputFormat: util.NewEnumFlag("", myUtil.InputFormats),
+	}
 
 	evalCommand := &cobra.Command{
 		Use:   "eval <query>",
@@ -117,14 +159,61 @@ To evaluate a query against JSON data supplied with a file:// URL:
 
     $ opa eval --data file:///path/to/file.json 'data'
 
+To evaluate a query against a bundle directory or a .tar.gz bundle, skipping
+hidden files:
+
+    $ opa eval --bundle bundle.tar.gz --ignore '.*' 'data.authz.allow'
+
+To verify the signature of a signed bundle:
+
+    $ opa eval --bundle bundle.tar.gz --verification-key public.pem 'data.authz.allow'
+
+To type check the policies against a JSON schema of the input:
+
+    $ opa eval --data policy.rego --input input.json --schema input-schema.json 'data.authz.allow'
+
+To evaluate a query against a Terraform file, the input format is taken from
+the file extension unless --input-format is set:
+
+    $ opa eval --data policy.rego --input main.tf 'data.terraform.deny'
+    $ opa eval --data policy.rego --stdin-input --input-format yaml 'data.k8s.deny' < deployment.yaml
+
+To evaluate a query with every line of a JSON Lines file as input, writing
+one JSON line per input with the result or the error:
+
+    $ opa eval --data policy.rego --inputs-jsonl records.jsonl --parallel 4 --format raw 'data.authz.allow'
+
+To re-evaluate a query on every change of the data and input files:
+
+    $ opa eval --watch --data policy.rego --input input.json 'data.authz.allow'
+
 `,
 
 		PreRunE: func(cmd *cobra.Command, args []string) error {
-			return validateEvalParams(&params, args)
+			if err := validateEvalParams(&params, args); err != nil {
+				return err
+			}
+			return validateJSONLParams(&params)
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 
-			_, err := eval(args, params, os.Stdout)
+			if params.inputsJSONL != "" {
+				if err := evalJSONL(args, params, os.Stdout); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					os.Exit(1)
+				}
+				return
+			}
+
+			if params.watch {
+				if err := watchEval(args, params, os.Stdout); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					os.Exit(1)
+				}
+				return
+			}
+
+			_, err := eval(args, params, os.Stdout, os.Stderr)
 			if err != nil {
 				fmt.Fprintln(os.Stderr, err)
 				os.Exit(1)
@@ -134,10 +223,20 @@ To evaluate a query against JSON data supplied with a file:// URL:
 	// Shared flags
 
 	addDataFlag(evalCommand.Flags(), &params.dataPaths)
+	addLoadFlags(evalCommand.Flags(), &params)
 	addInputFlag(evalCommand.Flags(), &params.inputPath)
+	addInputFormatFlag(evalCommand.Flags(), params.inputFormat)
 	addResultPathFlag(evalCommand.Flags(), &params.resultPath)
+	addResultPathLanguageFlag(evalCommand.Flags(), params.resultLang)
+	addOutputFormatFlag(evalCommand.Flags(), params.format)
 	addQueryStdinFlag(evalCommand.Flags(), &params.stdin)
 	addInputStdinFlag(evalCommand.Flags(), &params.stdinInput)
+	addExplainFlag(evalCommand.Flags(), params.explain)
+	addProfileFlags(evalCommand.Flags(), &params)
+	addCoverageFlag(evalCommand.Flags(), &params.coverage)
+	evalCommand.Flags().BoolVarP(&params.watch, "watch", "w", false, "re-evaluate the query on every change of the data and input files")
+	evalCommand.Flags().StringVarP(&params.inputsJSONL, "inputs-jsonl", "", "", "evaluate the query with every line of the JSON Lines file as input, - reads stdin")
+	evalCommand.Flags().IntVarP(&params.parallel, "parallel", "", 1, "set number of goroutines evaluating the --inputs-jsonl lines")
 
 	RootCommand.AddCommand(evalCommand)
 }
@@ -146,14 +245,35 @@ func addDataFlag(fs *pflag.FlagSet, paths *repeatedStringFlag) {
 	fs.VarP(paths, "data", "d", "set policy or data file(s). This flag can be repeated.")
 }
 
+// addLoadFlags adds the flags of the bundles, the ignored files and the
+// schemas loaded along with the data files.
+func addLoadFlags(fs *pflag.FlagSet, params *evalCommandParams) {
+	fs.VarP(&params.bundlePaths, "bundle", "b", "set bundle file(s) or directory path(s). This flag can be repeated.")
+	fs.StringSliceVarP(&params.ignore, "ignore", "", []string{}, "set file and directory names to ignore during loading (e.g., '.*' excludes hidden files)")
+	fs.StringVarP(&params.schemaPath, "schema", "s", "", "set schema file path or directory path")
+	addBundleVerificationFlags(fs, &params.verify)
+}
+
 func addInputFlag(fs *pflag.FlagSet, inputPath *string) {
 	fs.StringVarP(inputPath, "input", "i", "", "set input file path")
 }
 
+func addInputFormatFlag(fs *pflag.FlagSet, format *util.EnumFlag) {
+	fs.VarP(format, "input-format", "", "set input format, by default from the input file extension (.json, .yaml, .yml, .toml, .tf, .hcl) or JSON or YAML")
+}
+
 func addResultPathFlag(fs *pflag.FlagSet, resultPath *string) {
 	fs.StringVarP(resultPath, "resultPath", "r", "", "set result json path")
 }
 
+func addOutputFormatFlag(fs *pflag.FlagSet, format *util.EnumFlag) {
+	fs.VarP(format, "format", "f", "set output format")
+}
+
+func addResultPathLanguageFlag(fs *pflag.FlagSet, language *util.EnumFlag) {
+	fs.VarP(language, "resultPathLanguage", "", "set result path language")
+}
+
 func addQueryStdinFlag(fs *pflag.FlagSet, stdin *bool) {
 	fs.BoolVarP(stdin, "stdin", "", false, "read query from stdin")
 }
@@ -162,6 +282,20 @@ func addInputStdinFlag(fs *pflag.FlagSet, stdinInput *bool) {
 	fs.BoolVarP(stdinInput, "stdin-input", "I", false, "read input document from stdin")
 }
 
+func addExplainFlag(fs *pflag.FlagSet, explain *util.EnumFlag) {
+	fs.VarP(explain, "explain", "", "enable query explanations")
+}
+
+func addProfileFlags(fs *pflag.FlagSet, params *evalCommandParams) {
+	fs.BoolVarP(&params.profile, "profile", "", false, "perform expression profiling")
+	fs.IntVarP(&params.profileLimit, "profile-limit", "", 10, "set number of profiling results to show")
+	fs.VarP(&params.profileSort, "profile-sort", "", fmt.Sprintf("set sort order of expression profiler results (%s). This flag can be repeated.", strings.Join(myUtil.ProfileSortCriteria, ", ")))
+}
+
+func addCoverageFlag(fs *pflag.FlagSet, coverage *bool) {
+	fs.BoolVarP(coverage, "coverage", "", false, "report coverage")
+}
+
 func readInputBytes(params evalCommandParams) ([]byte, error) {
 	if params.stdinInput {
 		return io.ReadAll(os.Stdin)
@@ -171,16 +305,16 @@ func readInputBytes(params evalCommandParams) ([]byte, error) {
 	return nil, nil
 }
 
-func eval(args []string, params evalCommandParams, w io.Writer) (bool, error) {
+// prepareEval prepares the query of the eval flags and returns the evaluation
+// options with the input document.
+func prepareEval(ctx context.Context, args []string, params evalCommandParams) (rego.PreparedEvalQuery, []rego.EvalOption, error) {
 
 	var query string
 
-	ctx := context.Background()
-
 	if params.stdin {
 		bs, err := io.ReadAll(os.Stdin)
 		if err != nil {
-			return false, err
+			return rego.PreparedEvalQuery{}, nil, err
 		}
 		query = string(bs)
 	} else {
@@ -189,14 +323,30 @@ func eval(args []string, params evalCommandParams, w io.Writer) (bool, error) {
 
 	regoArgs := []func(*rego.Rego){rego.Query(query)}
 
-	if len(params.dataPaths.v) > 0 {
-		f := loaderFilter{
-			Ignore: []string{""},
-		}
+	f := loaderFilter{
+		Ignore: params.ignore,
+	}
 
+	if len(params.dataPaths.v) > 0 {
 		regoArgs = append(regoArgs, rego.Load(params.dataPaths.v, f.Apply))
 	}
 
+	bundles, err := loadBundles(params.bundlePaths.v, f.Apply, params.verify)
+	if err != nil {
+		return rego.PreparedEvalQuery{}, nil, err
+	}
+	for path, b := range bundles {
+		regoArgs = append(regoArgs, rego.ParsedBundle(path, b))
+	}
+
+	if params.schemaPath != "" {
+		schemas, err := loader.Schemas(params.schemaPath)
+		if err != nil {
+			return rego.PreparedEvalQuery{}, nil, err
+		}
+		regoArgs = append(regoArgs, rego.Schemas(schemas))
+	}
+
 	evalArgs := []rego.EvalOption{
 		rego.EvalRuleIndexing(true),
 		rego.EvalEarlyExit(true),
@@ -204,23 +354,61 @@ func eval(args []string, params evalCommandParams, w io.Writer) (bool, error) {
 
 	inputBytes, err := readInputBytes(params)
 	if err != nil {
-		return false, err
+		return rego.PreparedEvalQuery{}, nil, err
 	}
 	if inputBytes != nil {
-		var input interface{}
-		err := util.Unmarshal(inputBytes, &input)
+		name, format := "stdin", params.inputFormat.String()
+		if params.inputPath != "" {
+			name = params.inputPath
+			if format == "" {
+				format = myUtil.InputFormatFromPath(params.inputPath)
+			}
+		}
+		input, err := myUtil.ParseInput(name, inputBytes, format)
 		if err != nil {
-			return false, fmt.Errorf("unable to parse input: %s", err.Error())
+			return rego.PreparedEvalQuery{}, nil, fmt.Errorf("unable to parse input: %s", err.Error())
 		}
 		evalArgs = append(evalArgs, rego.EvalInput(input))
 	}
 
 	r := rego.New(regoArgs...)
 
-	var pq rego.PreparedEvalQuery
-	pq, resultErr := r.PrepareForEval(ctx)
-	if resultErr != nil {
-		return false, resultErr
+	pq, err := r.PrepareForEval(ctx)
+	if err != nil {
+		return rego.PreparedEvalQuery{}, nil, err
+	}
+
+	return pq, evalArgs, nil
+}
+
+// eval prints the result of the query to w. The trace, the profile and the
+// coverage follow the result on w with the pretty format, and go to errW with
+// the other formats so w only holds the result.
+func eval(args []string, params evalCommandParams, w, errW io.Writer) (bool, error) {
+
+	ctx := context.Background()
+
+	pq, evalArgs, err := prepareEval(ctx, args, params)
+	if err != nil {
+		return false, err
+	}
+
+	var buf *topdown.BufferTracer
+	if myUtil.IsExplain(params.explain.String()) {
+		buf = topdown.NewBufferTracer()
+		evalArgs = append(evalArgs, rego.EvalQueryTracer(buf))
+	}
+
+	var prof *profiler.Profiler
+	if params.profile {
+		prof = profiler.New()
+		evalArgs = append(evalArgs, rego.EvalQueryTracer(prof))
+	}
+
+	var cov *cover.Cover
+	if params.coverage {
+		cov = cover.New()
+		evalArgs = append(evalArgs, rego.EvalQueryTracer(cov))
 	}
 
 	result, resultErr := pq.Eval(ctx, evalArgs...)
@@ -228,27 +416,82 @@ func eval(args []string, params evalCommandParams, w io.Writer) (bool, error) {
 		return false, resultErr
 	}
 
-	if params.resultPath != "" {
-		parse := jsonpath.New("")
-		parse.EnableJSONOutput(false)
-		resultPathErr := parse.Parse(params.resultPath)
-		if resultPathErr != nil {
-			return false, resultPathErr
-		}
-		res := myUtil.ResultSetTArrayMap(result)
-		printErr := parse.Execute(w, res)
-		if printErr != nil {
-			return false, printErr
+	printErr := printResult(w, result, params)
+	if printErr != nil {
+		return false, printErr
+	}
+
+	reportW := w
+	if params.resultPath != "" || params.format.String() != evalFormatPretty {
+		reportW = errW
+	}
+
+	if buf != nil {
+		trace := myUtil.FilterTrace(*buf, params.explain.String())
+		if evalFormatIsJSON(params) {
+			traceJSON, err := myUtil.TraceJSON(trace)
+			if err != nil {
+				return false, err
+			}
+			fmt.Fprintln(reportW, traceJSON)
+		} else {
+			fmt.Fprintln(reportW)
+			fmt.Fprint(reportW, myUtil.PrettyTrace(trace))
 		}
-		return true, nil
 	}
-	encoder := json.NewEncoder(w)
-	encoder.SetIndent("", "  ")
-	res := myUtil.ResultSetTArrayMap(result)
-	resultJSONErr := encoder.Encode(res)
-	if resultJSONErr != nil {
-		return false, resultJSONErr
+
+	if prof != nil {
+		fmt.Fprintln(reportW)
+		stats := myUtil.SortProfile(prof.ReportTopNResults(0, nil), params.profileSort.v, params.profileLimit)
+		printProfile(reportW, stats)
+	}
+
+	if cov != nil {
+		fmt.Fprintln(reportW)
+		if err := printJSON(reportW, cov.Report(coverageModules(pq.Modules()))); err != nil {
+			return false, err
+		}
 	}
 
 	return true, nil
 }
+
+// evalFormatIsJSON reports whether the format prints JSON, the trace is then
+// printed as the JSON events.
+func evalFormatIsJSON(params evalCommandParams) bool {
+	switch params.format.String() {
+	case evalFormatJSON, evalFormatValues, evalFormatBindings:
+		return true
+	}
+	return false
+}
+
+// coverageModules keys the modules by the file name in their locations, which
+// is the name the coverage tracer sees and may differ from the loader key.
+func coverageModules(modules map[string]*ast.Module) map[string]*ast.Module {
+	res := make(map[string]*ast.Module, len(modules))
+	for name, module := range modules {
+		if module.Package != nil && module.Package.Location != nil && module.Package.Location.File != "" {
+			name = module.Package.Location.File
+		}
+		res[name] = module
+	}
+	return res
+}
+
+func printProfile(w io.Writer, stats []profiler.ExprStats) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tNUM EVAL\tNUM REDO\tLOCATION")
+	for _, stat := range stats {
+		fmt.Fprintf(tw, "%v\t%d\t%d\t%v\n", time.Duration(stat.ExprTimeNs), stat.NumEval, stat.NumRedo, stat.Location)
+	}
+	tw.Flush()
+}
+
+func printResult(w io.Writer, result rego.ResultSet, params evalCommandParams) error {
+	if params.resultPath != "" {
+		res := myUtil.ResultSetTArrayMap(result)
+		return myUtil.ExecuteResultPath(context.Background(), w, res, params.resultPath, params.resultLang.String())
+	}
+	return printFormatted(w, result, params.format.String())
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 Honyrik.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	myUtil "github.com/Honyrik/opa-go-service/util"
+	"github.com/open-policy-agent/opa/util"
+)
+
+// newEvalTestParams returns the params of eval with the defaults of the flags
+// and the policy written in a temporary directory as data.
+func newEvalTestParams(t *testing.T, policy string) evalCommandParams {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "policy.rego")
+	if err := os.WriteFile(file, []byte(policy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return evalCommandParams{
+		dataPaths:   newrepeatedStringFlag([]string{file}),
+		explain:     util.NewEnumFlag(myUtil.ExplainOff, myUtil.ExplainModes),
+		format:      util.NewEnumFlag(evalFormatJSON, evalFormats),
+		resultLang:  util.NewEnumFlag(myUtil.ResultPathJSONPath, myUtil.ResultPathLanguages),
+		inputFormat: util.NewEnumFlag("", myUtil.InputFormats),
+	}
+}
+
+func TestEvalReportsWriter(t *testing.T) {
+	tests := []struct {
+		format    string
+		jsonTrace bool
+	}{
+		{format: evalFormatPretty},
+		{format: evalFormatJSON, jsonTrace: true},
+		{format: evalFormatValues, jsonTrace: true},
+		{format: evalFormatBindings, jsonTrace: true},
+		{format: evalFormatRaw},
+		{format: evalFormatYAML},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.format, func(t *testing.T) {
+			params := newEvalTestParams(t, "package test\n\nallow { true }\n")
+			params.format.Set(tc.format)
+			params.explain.Set(myUtil.ExplainFull)
+			params.profile = true
+
+			var w, errW bytes.Buffer
+			if _, err := eval([]string{"data.test.allow"}, params, &w, &errW); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tc.format == evalFormatPretty {
+				if errW.Len() != 0 || !strings.Contains(w.String(), "NUM EVAL") {
+					t.Fatalf("expected the reports on the output but got %q and %q", w.String(), errW.String())
+				}
+				return
+			}
+
+			if strings.Contains(w.String(), "NUM EVAL") || strings.Contains(w.String(), "Enter") {
+				t.Fatalf("expected only the result on the output but got %q", w.String())
+			}
+			if !strings.Contains(errW.String(), "NUM EVAL") {
+				t.Fatalf("expected the profile on the error output but got %q", errW.String())
+			}
+
+			trace, _ := errW.ReadString('\n')
+			if tc.jsonTrace {
+				var value interface{}
+				if err := json.Unmarshal([]byte(w.String()), &value); err != nil {
+					t.Fatalf("expected one JSON document on the output: %v", err)
+				}
+				var events []myUtil.TraceEvent
+				if err := json.Unmarshal([]byte(trace), &events); err != nil || len(events) == 0 {
+					t.Fatalf("expected the JSON events of the trace but got %q", trace)
+				}
+			} else if !strings.Contains(errW.String(), "Enter data.test.allow") {
+				t.Fatalf("expected the pretty trace on the error output but got %q", errW.String())
+			}
+		})
+	}
+}
+
+func TestEvalCoverageWriter(t *testing.T) {
+	for _, format := range []string{evalFormatJSON, evalFormatPretty} {
+		t.Run(format, func(t *testing.T) {
+			params := newEvalTestParams(t, "package test\n\nallow { true }\n")
+			params.format.Set(format)
+			params.coverage = true
+
+			var w, errW bytes.Buffer
+			if _, err := eval([]string{"data.test.allow"}, params, &w, &errW); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			report := errW.String()
+			if format == evalFormatPretty {
+				report = w.String()
+			} else {
+				var value interface{}
+				if err := json.Unmarshal(w.Bytes(), &value); err != nil {
+					t.Fatalf("expected one JSON document on the output: %v", err)
+				}
+			}
+			if !strings.Contains(report, `"covered_lines"`) {
+				t.Fatalf("expected the coverage report but got %q", report)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright 2023 Honyrik.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+
+	pb "github.com/Honyrik/opa-go-service/grpc"
+	"github.com/labstack/echo"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/format"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+)
+
+type fmtCommandParams struct {
+	diff   bool
+	list   bool
+	write  bool
+	regoV1 bool
+}
+
+var futureKeywordsImport = ast.MustParseRef("future.keywords")
+
+// formatRego returns the canonical formatting of src. With regoV1 the rules
+// are rewritten with the if and contains keywords by importing
+// future.keywords, which is how this OPA version spells Rego v1 syntax.
+func formatRego(name string, src []byte, regoV1 bool) ([]byte, error) {
+	if !regoV1 {
+		return format.Source(name, src)
+	}
+
+	module, err := ast.ParseModuleWithOpts(name, string(src), ast.ParserOptions{ProcessAnnotation: true})
+	if err != nil {
+		return nil, err
+	}
+	if module == nil {
+		return nil, fmt.Errorf("%s: empty module", name)
+	}
+
+	var imports []*ast.Import
+	for _, imp := range module.Imports {
+		if path, ok := imp.Path.Value.(ast.Ref); !ok || !path.HasPrefix(futureKeywordsImport) {
+			imports = append(imports, imp)
+		}
+	}
+	module.Imports = append([]*ast.Import{{Path: ast.NewTerm(futureKeywordsImport.Copy())}}, imports...)
+
+	return format.Ast(module)
+}
+
+func diffRego(name string, src, formatted []byte) (string, error) {
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(src)),
+		B:        difflib.SplitLines(string(formatted)),
+		FromFile: name,
+		ToFile:   name + " (formatted)",
+		Context:  3,
+	})
+}
+
+func FormatRego(ctx context.Context, in *pb.FormatRequest) (*pb.FormatResult, error) {
+	if len(in.Packages) == 0 {
+		return &pb.FormatResult{
+			IsSuccess: false,
+			Error:     "Need packages",
+		}, nil
+	}
+
+	res := &pb.FormatResult{
+		IsSuccess: true,
+	}
+
+	for index, module := range in.Packages {
+		name := fmt.Sprintf("rego_%d.rego", index)
+		formatted, err := formatRego(name, []byte(module), in.RegoV1)
+		if err != nil {
+			res.IsSuccess = false
+			res.Packages = append(res.Packages, &pb.FormattedPackage{
+				Source: module,
+				Error:  err.Error(),
+			})
+			continue
+		}
+
+		pkg := &pb.FormattedPackage{
+			Source:  string(formatted),
+			Changed: !bytes.Equal(formatted, []byte(module)),
+		}
+		if in.Diff && pkg.Changed {
+			pkg.Diff, err = diffRego(name, []byte(module), formatted)
+			if err != nil {
+				return &pb.FormatResult{
+					IsSuccess: false,
+					Error:     fmt.Sprintf("Unable Diff: %v", err),
+				}, nil
+			}
+		}
+		res.Packages = append(res.Packages, pkg)
+	}
+
+	return res, nil
+}
+
+func (s *server) Format(ctx context.Context, in *pb.FormatRequest) (*pb.FormatResult, error) {
+	return FormatRego(ctx, in)
+}
+
+func Format(c echo.Context) error {
+	data := new(pb.FormatRequest)
+	err := c.Bind(data)
+	if err != nil {
+		c.JSON(http.StatusOK, &pb.FormatResult{
+			IsSuccess: false,
+			Error:     fmt.Sprintf("Unable Post Data: %v", err),
+		})
+		return nil
+	}
+	res, err := FormatRego(c.Request().Context(), data)
+
+	if err != nil {
+		c.JSON(http.StatusOK, &pb.FormatResult{
+			IsSuccess: false,
+			Error:     fmt.Sprintf("Unable Format Rego: %v", err),
+		})
+		return nil
+	}
+
+	c.JSON(http.StatusOK, res)
+	return nil
+}
+
+func init() {
+
+	params := fmtCommandParams{}
+
+	fmtCommand := &cobra.Command{
+		Use:   "fmt [path [...]]",
+		Short: "Format Rego source files",
+		Long: `Format Rego source files.
+
+The fmt command takes Rego source files and prints them in the canonical
+format. With no path the source is read from stdin.
+
+Examples
+--------
+
+    $ opa-go-service fmt --diff policy/
+
+    $ opa-go-service fmt --write --rego-v1 policy/
+`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if params.write && len(args) == 0 {
+				return errors.New("specify --write with at least one path")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			_, err := formatFiles(args, params, os.Stdout)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	fmtCommand.Flags().BoolVarP(&params.diff, "diff", "d", false, "only print the diff between the file and its formatting")
+	fmtCommand.Flags().BoolVarP(&params.list, "list", "l", false, "list files whose formatting differs")
+	fmtCommand.Flags().BoolVarP(&params.write, "write", "w", false, "overwrite the original file with the formatting")
+	fmtCommand.Flags().BoolVarP(&params.regoV1, "rego-v1", "", false, "rewrite rules with the Rego v1 keywords (if, contains)")
+	RootCommand.AddCommand(fmtCommand)
+}
+
+func formatFiles(args []string, params fmtCommandParams, w io.Writer) (bool, error) {
+	if len(args) == 0 {
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return false, err
+		}
+		formatted, err := formatRego("stdin", src, params.regoV1)
+		if err != nil {
+			return false, err
+		}
+		return true, formatOutput(w, "stdin", src, formatted, params)
+	}
+
+	sources, err := readRegoSources(args)
+	if err != nil {
+		return false, err
+	}
+
+	var names []string
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		src := []byte(sources[name])
+		formatted, err := formatRego(name, src, params.regoV1)
+		if err != nil {
+			return false, err
+		}
+		if err := formatOutput(w, name, src, formatted, params); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func formatOutput(w io.Writer, name string, src, formatted []byte, params fmtCommandParams) error {
+	changed := !bytes.Equal(src, formatted)
+
+	if params.list {
+		if changed {
+			fmt.Fprintln(w, name)
+		}
+	} else if params.diff {
+		if changed {
+			diff, err := diffRego(name, src, formatted)
+			if err != nil {
+				return err
+			}
+			fmt.Fprint(w, diff)
+		}
+	} else if !params.write {
+		_, err := w.Write(formatted)
+		return err
+	}
+
+	if params.write && changed {
+		info, err := os.Stat(name)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(name, formatted, info.Mode())
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+// Copyright 2023 Honyrik.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	myUtil "github.com/Honyrik/opa-go-service/util"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/util"
+)
+
+// jsonlResultFormats are the shapes of the result of an output line by the
+// output format of eval.
+var jsonlResultFormats = map[string]string{
+	evalFormatJSON:     myUtil.ResultFormatLegacy,
+	evalFormatValues:   myUtil.ResultFormatValues,
+	evalFormatBindings: myUtil.ResultFormatBindings,
+	evalFormatRaw:      myUtil.ResultFormatFirst,
+}
+
+type jsonlResult struct {
+	Line   int         `json:"line"`
+	Result interface{} `json:"result"`
+}
+
+type jsonlError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// jsonlRecord is a line of the inputs, done receives its output line.
+type jsonlRecord struct {
+	line  int
+	input []byte
+	done  chan []byte
+}
+
+func validateJSONLParams(p *evalCommandParams) error {
+	if p.inputsJSONL == "" {
+		if p.parallel != 1 {
+			return errors.New("specify --parallel with --inputs-jsonl")
+		}
+		return nil
+	}
+	if p.inputPath != "" || p.stdinInput {
+		return errors.New("specify --inputs-jsonl or --input but not both")
+	}
+	if f := p.inputFormat.String(); f != "" && f != myUtil.InputFormatJSON {
+		return errors.New("specify --input-format json or none with --inputs-jsonl")
+	}
+	if p.inputsJSONL == "-" && p.stdin {
+		return errors.New("specify --stdin or --inputs-jsonl - but not both")
+	}
+	if p.resultPath != "" {
+		return errors.New("specify --inputs-jsonl or --resultPath but not both")
+	}
+	if _, ok := jsonlResultFormats[p.format.String()]; !ok {
+		return errors.New("specify --format json, values, bindings or raw with --inputs-jsonl")
+	}
+	if myUtil.IsExplain(p.explain.String()) || p.profile || p.coverage || p.watch {
+		return errors.New("specify --inputs-jsonl without --explain, --profile, --coverage and --watch")
+	}
+	if p.parallel < 1 {
+		return errors.New("specify --parallel of one or more")
+	}
+	return nil
+}
+
+// evalJSONL prepares the query once and evaluates it with every line of the
+// inputs file as input, "-" reads stdin. One output line is written per input
+// line in the order of the inputs, with the result or the error of the line.
+func evalJSONL(args []string, params evalCommandParams, w io.Writer) error {
+	ctx := context.Background()
+
+	pq, evalArgs, err := prepareEval(ctx, args, params)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if params.inputsJSONL != "-" {
+		f, err := os.Open(params.inputsJSONL)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	format := jsonlResultFormats[params.format.String()]
+
+	records := make(chan *jsonlRecord, params.parallel)
+	queue := make(chan *jsonlRecord, 2*params.parallel)
+
+	var wg sync.WaitGroup
+	for i := 0; i < params.parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for record := range records {
+				record.done <- evalJSONLRecord(ctx, pq, evalArgs, format, record)
+			}
+		}()
+	}
+
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(queue)
+		defer close(records)
+		readErr <- readJSONL(r, func(record *jsonlRecord) {
+			queue <- record
+			records <- record
+		})
+	}()
+
+	bw := bufio.NewWriter(w)
+	for record := range queue {
+		bw.Write(<-record.done)
+		if len(queue) == 0 {
+			bw.Flush()
+		}
+	}
+	wg.Wait()
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return <-readErr
+}
+
+// readJSONL calls fn with every line of r that is not blank, lines are
+// numbered from one.
+func readJSONL(r io.Reader, fn func(record *jsonlRecord)) error {
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		bs, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(bs)) > 0 {
+			fn(&jsonlRecord{line: line, input: bs, done: make(chan []byte, 1)})
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func evalJSONLRecord(ctx context.Context, pq rego.PreparedEvalQuery, evalArgs []rego.EvalOption, format string, record *jsonlRecord) []byte {
+	var input interface{}
+	if err := util.UnmarshalJSON(record.input, &input); err != nil {
+		return jsonlLine(record.line, jsonlError{Line: record.line, Error: fmt.Sprintf("unable to parse input: %v", err)})
+	}
+
+	result, err := pq.Eval(ctx, append(evalArgs[:len(evalArgs):len(evalArgs)], rego.EvalInput(input))...)
+	if err != nil {
+		return jsonlLine(record.line, jsonlError{Line: record.line, Error: err.Error()})
+	}
+
+	res, err := myUtil.ResultSetFormat(result, format)
+	if err != nil {
+		return jsonlLine(record.line, jsonlError{Line: record.line, Error: err.Error()})
+	}
+	return jsonlLine(record.line, jsonlResult{Line: record.line, Result: res})
+}
+
+func jsonlLine(line int, v interface{}) []byte {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		bs, _ = json.Marshal(jsonlError{Line: line, Error: fmt.Sprintf("Unable Json: %v", err)})
+	}
+	return append(bs, '\n')
+}
@@ -0,0 +1,142 @@
+// Copyright 2023 Honyrik.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	myUtil "github.com/Honyrik/opa-go-service/util"
+)
+
+// newJSONLTestParams returns the params of eval --inputs-jsonl with the lines
+// written in a temporary file.
+func newJSONLTestParams(t *testing.T, policy string, lines []string) evalCommandParams {
+	t.Helper()
+
+	params := newEvalTestParams(t, policy)
+	params.inputsJSONL = filepath.Join(t.TempDir(), "inputs.jsonl")
+	params.parallel = 1
+	if err := os.WriteFile(params.inputsJSONL, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return params
+}
+
+func TestEvalJSONLOrder(t *testing.T) {
+	var lines []string
+	var expected []string
+	for i := 1; i <= 200; i++ {
+		lines = append(lines, fmt.Sprintf(`{"n": %d}`, i))
+		expected = append(expected, fmt.Sprintf(`{"line":%d,"result":%d}`, i, 2*i))
+	}
+
+	params := newJSONLTestParams(t, "package test\n\ndouble = input.n * 2\n", lines)
+	params.format.Set(evalFormatRaw)
+	params.parallel = 8
+
+	var w bytes.Buffer
+	if err := evalJSONL([]string{"data.test.double"}, params, &w); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result := strings.Split(strings.TrimSuffix(w.String(), "\n"), "\n")
+	if len(result) != len(expected) {
+		t.Fatalf("expected %d lines but got %d", len(expected), len(result))
+	}
+	for i := range expected {
+		if result[i] != expected[i] {
+			t.Fatalf("expected the line %s but got %s", expected[i], result[i])
+		}
+	}
+}
+
+func TestEvalJSONLMalformedRecords(t *testing.T) {
+	params := newJSONLTestParams(t, "package test\n\nname = input.name\n", []string{
+		`{"name": "alice"}`,
+		`{"name": `,
+		``,
+		`not json`,
+		`{"name": "bob"}`,
+	})
+	params.format.Set(evalFormatRaw)
+	params.parallel = 2
+
+	var w bytes.Buffer
+	if err := evalJSONL([]string{"data.test.name"}, params, &w); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var results []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSuffix(w.String(), "\n"), "\n") {
+		var result map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &result); err != nil {
+			t.Fatalf("expected a JSON line but got %q: %v", line, err)
+		}
+		results = append(results, result)
+	}
+	if len(results) != 4 {
+		t.Fatalf("expected 4 lines but got %v", results)
+	}
+	for i, line := range []float64{1, 2, 4, 5} {
+		if results[i]["line"] != line {
+			t.Fatalf("expected the line %v but got %v", line, results[i])
+		}
+	}
+	for _, i := range []int{1, 2} {
+		if msg, _ := results[i]["error"].(string); !strings.Contains(msg, "unable to parse input") {
+			t.Fatalf("expected the error of the malformed record but got %v", results[i])
+		}
+	}
+	for i, name := range map[int]string{0: "alice", 3: "bob"} {
+		if results[i]["result"] != name {
+			t.Fatalf("expected the result %s but got %v", name, results[i])
+		}
+	}
+}
+
+func TestValidateJSONLParams(t *testing.T) {
+	tests := []struct {
+		note     string
+		update   func(p *evalCommandParams)
+		expected string
+	}{
+		{note: "valid", update: func(p *evalCommandParams) { p.parallel = 4 }},
+		{note: "input", update: func(p *evalCommandParams) { p.inputPath = "input.json" }, expected: "--input but not both"},
+		{note: "stdin input", update: func(p *evalCommandParams) { p.stdinInput = true }, expected: "--input but not both"},
+		{note: "input format", update: func(p *evalCommandParams) { p.inputFormat.Set(myUtil.InputFormatYAML) }, expected: "--input-format"},
+		{note: "stdin", update: func(p *evalCommandParams) { p.inputsJSONL = "-"; p.stdin = true }, expected: "--stdin"},
+		{note: "result path", update: func(p *evalCommandParams) { p.resultPath = "$.x" }, expected: "--resultPath"},
+		{note: "pretty", update: func(p *evalCommandParams) { p.format.Set(evalFormatPretty) }, expected: "--format"},
+		{note: "explain", update: func(p *evalCommandParams) { p.explain.Set(myUtil.ExplainFull) }, expected: "--explain"},
+		{note: "profile", update: func(p *evalCommandParams) { p.profile = true }, expected: "--profile"},
+		{note: "coverage", update: func(p *evalCommandParams) { p.coverage = true }, expected: "--coverage"},
+		{note: "watch", update: func(p *evalCommandParams) { p.watch = true }, expected: "--watch"},
+		{note: "parallel zero", update: func(p *evalCommandParams) { p.parallel = 0 }, expected: "--parallel of one or more"},
+		{note: "parallel without inputs", update: func(p *evalCommandParams) { p.inputsJSONL = ""; p.parallel = 2 }, expected: "--parallel with --inputs-jsonl"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			params := newJSONLTestParams(t, "package test\n", nil)
+			tc.update(&params)
+
+			err := validateJSONLParams(&params)
+			if tc.expected == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Fatalf("expected the error %s but got %v", tc.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,379 @@
+// Copyright 2023 Honyrik.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Honyrik/opa-go-service/client"
+	pb "github.com/Honyrik/opa-go-service/grpc"
+	"github.com/open-policy-agent/opa/util"
+	"github.com/spf13/cobra"
+)
+
+const (
+	loadtestTransportGRPC = "grpc"
+	loadtestTransportREST = "rest"
+)
+
+// loadtestBuckets are the upper bounds of the latency histogram, the last
+// bucket counts everything above.
+var loadtestBuckets = []time.Duration{
+	time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	20 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	200 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2 * time.Second,
+	5 * time.Second,
+}
+
+// maxLoadtestRate is the highest --rate, the interval between two requests
+// is at least a nanosecond.
+const maxLoadtestRate = 1e9
+
+type loadtestCommandParams struct {
+	target      string
+	transport   *util.EnumFlag
+	concurrency int
+	rate        float64
+	duration    time.Duration
+	timeout     time.Duration
+	format      *util.EnumFlag
+}
+
+type loadtestBucket struct {
+	Le    string `json:"le"`
+	Count int64  `json:"count"`
+}
+
+type loadtestResult struct {
+	Requests   int64            `json:"requests"`
+	Succeeded  int64            `json:"succeeded"`
+	Errors     map[string]int64 `json:"errors,omitempty"`
+	DurationNs int64            `json:"duration_ns"`
+	Rate       float64          `json:"rate"`
+	MinNs      int64            `json:"min_ns"`
+	MeanNs     int64            `json:"mean_ns"`
+	P50Ns      int64            `json:"p50_ns"`
+	P90Ns      int64            `json:"p90_ns"`
+	P99Ns      int64            `json:"p99_ns"`
+	MaxNs      int64            `json:"max_ns"`
+	Histogram  []loadtestBucket `json:"histogram"`
+}
+
+func init() {
+
+	params := loadtestCommandParams{
+		transport: util.NewEnumFlag(loadtestTransportGRPC, []string{loadtestTransportGRPC, loadtestTransportREST}),
+		format:    util.NewEnumFlag(benchFormatTable, []string{benchFormatTable, evalFormatJSON}),
+	}
+
+	loadtestCommand := &cobra.Command{
+		Use:   "loadtest <template> [template [...]]",
+		Short: "Replay requests against a running server",
+		Long: `Replay ApiRequest templates against a running server.
+
+Each template file holds an ApiRequest as JSON, the same body the /execute
+route takes, or an array of them. The requests are sent in turn by
+--concurrency workers for --duration, at most --rate requests per second in
+total when the rate is set. The report has the latency histogram and the
+count of each error class.
+
+Examples
+--------
+
+    $ opa-go-service loadtest --target localhost:8000 --concurrency 20 --duration 30s request.json
+
+    $ opa-go-service loadtest --transport rest --target http://localhost:8080 --rate 500 request.json
+`,
+
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateLoadtestParams(args, params)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			_, err := loadtest(args, params, os.Stdout)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	loadtestCommand.Flags().StringVarP(&params.target, "target", "", "", "set server address (default localhost:8000 for grpc, http://localhost:8080 for rest)")
+	loadtestCommand.Flags().VarP(params.transport, "transport", "", "set transport")
+	loadtestCommand.Flags().IntVarP(&params.concurrency, "concurrency", "c", 10, "set number of concurrent workers")
+	loadtestCommand.Flags().Float64VarP(&params.rate, "rate", "", 0, "set target requests per second, zero is unlimited")
+	loadtestCommand.Flags().DurationVarP(&params.duration, "duration", "", 10*time.Second, "set duration of the test")
+	loadtestCommand.Flags().DurationVarP(&params.timeout, "timeout", "t", 10*time.Second, "set timeout of each request")
+	addOutputFormatFlag(loadtestCommand.Flags(), params.format)
+
+	RootCommand.AddCommand(loadtestCommand)
+}
+
+func loadtest(args []string, params loadtestCommandParams, w io.Writer) (bool, error) {
+	templates, err := readLoadtestTemplates(args)
+	if err != nil {
+		return false, err
+	}
+
+	opts := []client.Option{
+		client.WithTimeout(params.timeout),
+		client.WithRetry(1, 0, 0),
+		client.WithMaxConns(params.concurrency),
+	}
+
+	var c *client.Client
+	switch params.transport.String() {
+	case loadtestTransportREST:
+		target := params.target
+		if target == "" {
+			target = "http://localhost:8080"
+		}
+		c = client.NewREST(target, opts...)
+	default:
+		target := params.target
+		if target == "" {
+			target = "localhost:8000"
+		}
+		c, err = client.NewGRPC(target, opts...)
+		if err != nil {
+			return false, err
+		}
+	}
+	defer c.Close()
+
+	res := runLoadtest(context.Background(), c, templates, params)
+
+	if params.format.String() == evalFormatJSON {
+		return true, printJSON(w, res)
+	}
+	printLoadtest(w, res)
+	return true, nil
+}
+
+// readLoadtestTemplates reads the requests of the template files, a file holds
+// one request object or an array of them.
+func readLoadtestTemplates(paths []string) ([]*pb.ApiRequest, error) {
+	var templates []*pb.ApiRequest
+	for _, path := range paths {
+		bs, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		bs = bytes.TrimSpace(bs)
+		if len(bs) > 0 && bs[0] == '[' {
+			var requests []*pb.ApiRequest
+			if err := json.Unmarshal(bs, &requests); err != nil {
+				return nil, fmt.Errorf("%s: %v", path, err)
+			}
+			templates = append(templates, requests...)
+			continue
+		}
+		in := new(pb.ApiRequest)
+		if err := json.Unmarshal(bs, in); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		templates = append(templates, in)
+	}
+	if len(templates) == 0 {
+		return nil, errors.New("no request in the templates")
+	}
+	return templates, nil
+}
+
+func validateLoadtestParams(args []string, params loadtestCommandParams) error {
+	if len(args) == 0 {
+		return errors.New("specify at least one template")
+	}
+	if params.concurrency < 1 {
+		return errors.New("specify --concurrency of one or more")
+	}
+	if !(params.rate >= 0 && params.rate <= maxLoadtestRate) {
+		return fmt.Errorf("specify --rate between zero and %g", maxLoadtestRate)
+	}
+	if params.rate > 0 && float64(time.Second)/params.rate >= math.MaxInt64 {
+		return fmt.Errorf("specify --rate of zero or at least %g", float64(time.Second)/math.MaxInt64)
+	}
+	if params.duration <= 0 {
+		return errors.New("specify a positive --duration")
+	}
+	return nil
+}
+
+// loadtestInterval returns the interval between two requests at rate, zero
+// when the rate is unlimited.
+func loadtestInterval(rate float64) time.Duration {
+	if rate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / rate)
+}
+
+// runLoadtest sends the templates in turn until the duration of the params
+// elapses. Requests in flight at the end are waited for.
+func runLoadtest(ctx context.Context, c *client.Client, templates []*pb.ApiRequest, params loadtestCommandParams) *loadtestResult {
+	ctx, cancel := context.WithTimeout(ctx, params.duration)
+	defer cancel()
+
+	var ticks <-chan time.Time
+	if interval := loadtestInterval(params.rate); interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	var seq uint64
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var latencies []time.Duration
+	errs := map[string]int64{}
+
+	start := time.Now()
+	for index := 0; index < params.concurrency; index++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var local []time.Duration
+			localErrs := map[string]int64{}
+			for {
+				if ticks != nil {
+					select {
+					case <-ctx.Done():
+					case <-ticks:
+					}
+				}
+				if ctx.Err() != nil {
+					break
+				}
+
+				in := templates[(atomic.AddUint64(&seq, 1)-1)%uint64(len(templates))]
+				t0 := time.Now()
+				_, err := c.Execute(context.Background(), in)
+				local = append(local, time.Since(t0))
+
+				var clientErr *client.Error
+				if errors.As(err, &clientErr) {
+					localErrs[clientErr.Code]++
+				} else if err != nil {
+					localErrs[client.CodeTransport]++
+				}
+			}
+
+			mu.Lock()
+			latencies = append(latencies, local...)
+			for class, count := range localErrs {
+				errs[class] += count
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	return loadtestReport(latencies, errs, elapsed)
+}
+
+func loadtestReport(latencies []time.Duration, errs map[string]int64, elapsed time.Duration) *loadtestResult {
+	res := &loadtestResult{
+		Requests:   int64(len(latencies)),
+		DurationNs: elapsed.Nanoseconds(),
+		Rate:       float64(len(latencies)) / elapsed.Seconds(),
+	}
+	if len(errs) > 0 {
+		res.Errors = errs
+	}
+
+	res.Succeeded = res.Requests
+	for _, count := range errs {
+		res.Succeeded -= count
+	}
+
+	counts := make([]int64, len(loadtestBuckets)+1)
+	for _, latency := range latencies {
+		counts[sort.Search(len(loadtestBuckets), func(i int) bool { return latency <= loadtestBuckets[i] })]++
+	}
+	for index, bound := range loadtestBuckets {
+		res.Histogram = append(res.Histogram, loadtestBucket{Le: bound.String(), Count: counts[index]})
+	}
+	res.Histogram = append(res.Histogram, loadtestBucket{Le: "+Inf", Count: counts[len(loadtestBuckets)]})
+
+	if len(latencies) == 0 {
+		return res
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	var total time.Duration
+	for _, latency := range latencies {
+		total += latency
+	}
+	res.MinNs = latencies[0].Nanoseconds()
+	res.MeanNs = total.Nanoseconds() / res.Requests
+	res.P50Ns = percentile(latencies, 50).Nanoseconds()
+	res.P90Ns = percentile(latencies, 90).Nanoseconds()
+	res.P99Ns = percentile(latencies, 99).Nanoseconds()
+	res.MaxNs = latencies[len(latencies)-1].Nanoseconds()
+
+	return res
+}
+
+func printLoadtest(w io.Writer, res *loadtestResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "requests\t%d\n", res.Requests)
+	fmt.Fprintf(tw, "succeeded\t%d\n", res.Succeeded)
+	fmt.Fprintf(tw, "duration\t%v\n", time.Duration(res.DurationNs))
+	fmt.Fprintf(tw, "rate\t%.2f/s\n", res.Rate)
+	fmt.Fprintf(tw, "min\t%v\n", time.Duration(res.MinNs))
+	fmt.Fprintf(tw, "mean\t%v\n", time.Duration(res.MeanNs))
+	fmt.Fprintf(tw, "p50\t%v\n", time.Duration(res.P50Ns))
+	fmt.Fprintf(tw, "p90\t%v\n", time.Duration(res.P90Ns))
+	fmt.Fprintf(tw, "p99\t%v\n", time.Duration(res.P99Ns))
+	fmt.Fprintf(tw, "max\t%v\n", time.Duration(res.MaxNs))
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LATENCY\tCOUNT")
+	for _, bucket := range res.Histogram {
+		fmt.Fprintf(tw, "<= %s\t%d\n", bucket.Le, bucket.Count)
+	}
+	tw.Flush()
+
+	if len(res.Errors) == 0 {
+		return
+	}
+
+	var classes []string
+	for class := range res.Errors {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ERROR\tCOUNT")
+	for _, class := range classes {
+		fmt.Fprintf(tw, "%s\t%d\n", class, res.Errors[class])
+	}
+	tw.Flush()
+}
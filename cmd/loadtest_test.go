@@ -0,0 +1,84 @@
+// Copyright 2023 Honyrik.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Honyrik/opa-go-service/client"
+	pb "github.com/Honyrik/opa-go-service/grpc"
+)
+
+func TestValidateLoadtestRate(t *testing.T) {
+	tests := []struct {
+		note  string
+		rate  float64
+		valid bool
+	}{
+		{note: "unlimited", rate: 0, valid: true},
+		{note: "rate", rate: 500, valid: true},
+		{note: "highest", rate: 1e9, valid: true},
+		{note: "lowest", rate: 1.1e-10, valid: true},
+		{note: "negative", rate: -1},
+		{note: "above the highest", rate: 2e9},
+		{note: "below the lowest", rate: 1e-10},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			params := loadtestCommandParams{concurrency: 1, rate: tc.rate, duration: time.Second}
+			err := validateLoadtestParams([]string{"request.json"}, params)
+			if tc.valid && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tc.valid && err == nil {
+				t.Fatal("expected an error")
+			}
+			if tc.valid && tc.rate > 0 && loadtestInterval(tc.rate) <= 0 {
+				t.Fatalf("expected a positive interval but got %v", loadtestInterval(tc.rate))
+			}
+		})
+	}
+}
+
+func TestLoadtestInterval(t *testing.T) {
+	tests := []struct {
+		rate     float64
+		expected time.Duration
+	}{
+		{rate: 0, expected: 0},
+		{rate: 1, expected: time.Second},
+		{rate: 500, expected: 2 * time.Millisecond},
+		{rate: 1e9, expected: time.Nanosecond},
+	}
+
+	for _, tc := range tests {
+		if interval := loadtestInterval(tc.rate); interval != tc.expected {
+			t.Errorf("rate %g: expected %v but got %v", tc.rate, tc.expected, interval)
+		}
+	}
+}
+
+func TestRunLoadtest(t *testing.T) {
+	ts := httptest.NewServer(newRestHandler(defaultDocsPath))
+	defer ts.Close()
+	c := client.NewREST(ts.URL, client.WithHTTPClient(ts.Client()))
+	defer c.Close()
+
+	templates := []*pb.ApiRequest{{Query: "x = 1"}}
+	for _, rate := range []float64{0, 100} {
+		params := loadtestCommandParams{concurrency: 2, rate: rate, duration: 100 * time.Millisecond}
+		res := runLoadtest(context.Background(), c, templates, params)
+		if res.Requests == 0 || res.Succeeded != res.Requests {
+			t.Fatalf("rate %g: expected successful requests but got %+v", rate, res)
+		}
+		if rate > 0 && res.Requests > 11 {
+			t.Fatalf("rate %g: expected at most 11 requests but got %d", rate, res.Requests)
+		}
+	}
+}
@@ -0,0 +1,400 @@
+// Copyright 2023 Honyrik.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	_ "embed"
+	"html/template"
+	"net/http"
+	"sync"
+
+	pb "github.com/Honyrik/opa-go-service/grpc"
+	"github.com/labstack/echo"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	defaultDocsPath = "/docs"
+	openAPIPath     = "/openapi.json"
+)
+
+//go:embed static/docs.html
+var docsPage string
+
+// docsTemplate escapes the URL of the OpenAPI document for the attributes
+// and the script of the docs page.
+var docsTemplate = template.Must(template.New("docs").Parse(docsPage))
+
+var openAPIDocument map[string]interface{}
+var openAPIDocumentOnce sync.Once
+
+// registerOpenAPI serves the OpenAPI document and the API docs page at
+// docsPath.
+func registerOpenAPI(mux *echo.Echo, docsPath string) {
+	mux.GET(openAPIPath, func(c echo.Context) error {
+		openAPIDocumentOnce.Do(func() {
+			openAPIDocument = newOpenAPIDocument()
+		})
+		return c.JSON(http.StatusOK, openAPIDocument)
+	})
+	mux.GET(docsPath, func(c echo.Context) error {
+		var buf bytes.Buffer
+		err := docsTemplate.Execute(&buf, struct{ OpenAPIURL string }{openAPIPath})
+		if err != nil {
+			return err
+		}
+		return c.HTMLBlob(http.StatusOK, buf.Bytes())
+	})
+}
+
+func schemaRef(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": schema},
+	}
+}
+
+// messageSchema adds the schema of the message, and of the messages of its
+// fields, to schemas and returns its reference. The field names are the proto
+// names, as the REST routes encode the pb types with encoding/json.
+func messageSchema(md protoreflect.MessageDescriptor, schemas map[string]interface{}) map[string]interface{} {
+	name := string(md.Name())
+	if _, exist := schemas[name]; exist {
+		return schemaRef(name)
+	}
+
+	properties := map[string]interface{}{}
+	schemas[name] = map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		schema := fieldSchema(fd, schemas)
+		if fd.IsList() {
+			schema = map[string]interface{}{"type": "array", "items": schema}
+		}
+		properties[string(fd.Name())] = schema
+	}
+
+	return schemaRef(name)
+}
+
+func fieldSchema(fd protoreflect.FieldDescriptor, schemas map[string]interface{}) map[string]interface{} {
+	if fd.IsMap() {
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": fieldSchema(fd.MapValue(), schemas),
+		}
+	}
+
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]interface{}{"type": "boolean"}
+	case protoreflect.StringKind:
+		return map[string]interface{}{"type": "string"}
+	case protoreflect.BytesKind:
+		return map[string]interface{}{"type": "string", "format": "byte"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.EnumKind:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case protoreflect.FloatKind:
+		return map[string]interface{}{"type": "number", "format": "float"}
+	case protoreflect.DoubleKind:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return messageSchema(fd.Message(), schemas)
+	}
+	return map[string]interface{}{}
+}
+
+// newOpenAPIDocument generates the OpenAPI 3 document of the REST routes from
+// the Api service of service.proto and of the OPA REST API.
+func newOpenAPIDocument() map[string]interface{} {
+	schemas := map[string]interface{}{}
+	paths := map[string]interface{}{}
+
+	service := pb.File_service_proto.Services().ByName("Api")
+	for _, route := range restRoutes {
+		method := service.Methods().ByName(protoreflect.Name(route.method))
+		paths[route.path] = map[string]interface{}{
+			"post": map[string]interface{}{
+				"operationId": route.method,
+				"tags":        []string{"Api"},
+				"summary":     "Api." + route.method + " over REST",
+				"description": "Failures are returned with isSuccess false and the error message, the HTTP status is 200.",
+				"requestBody": map[string]interface{}{
+					"required": true,
+					"content":  jsonContent(messageSchema(method.Input(), schemas)),
+				},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Result of " + route.method,
+						"content":     jsonContent(messageSchema(method.Output(), schemas)),
+					},
+				},
+			},
+		}
+	}
+
+	for path, item := range dataAPIPaths() {
+		paths[path] = item
+	}
+	for name, schema := range dataAPISchemas() {
+		schemas[name] = schema
+	}
+
+	paths["/datasources"] = map[string]interface{}{
+		"get": map[string]interface{}{
+			"operationId": "DataSources",
+			"tags":        []string{"Data sources"},
+			"summary":     "Status of the HTTP data sources",
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "Status of every source",
+					"content": jsonContent(map[string]interface{}{
+						"type":  "array",
+						"items": schemaRef("DataSourceStatus"),
+					}),
+				},
+			},
+		},
+	}
+	schemas["DataSourceStatus"] = dataSourceStatusSchema()
+
+	paths["/metrics"] = map[string]interface{}{
+		"get": map[string]interface{}{
+			"operationId": "Metrics",
+			"tags":        []string{"Data sources"},
+			"summary":     "Metrics of the server",
+			"description": "Counters (counter_*), timers (timer_*_ns) and gauges (gauge_*) by name, such as counter_data_source_<name>_updates.",
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "Value of every metric",
+					"content": jsonContent(map[string]interface{}{
+						"type":                 "object",
+						"additionalProperties": map[string]interface{}{"type": "integer", "format": "int64"},
+					}),
+				},
+			},
+		},
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "opa-go-service",
+			"version": version,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+		},
+	}
+}
+
+func queryParameter(name, description string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":        name,
+		"in":          "query",
+		"description": description,
+		"schema":      map[string]interface{}{"type": "string"},
+	}
+}
+
+// nestedPathParameter is the path parameter of a nested path, such as a/b/c
+// for /v1/data/a/b/c. OpenAPI path parameters are a single segment, so the
+// parameter is marked with x-nested-path: its slashes are sent as is and must
+// not be percent-encoded.
+func nestedPathParameter(name, description string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":          name,
+		"in":            "path",
+		"required":      true,
+		"description":   description + ". It spans one or more segments separated by unescaped slashes, such as a/b/c, like the paths of the OPA REST API.",
+		"schema":        map[string]interface{}{"type": "string"},
+		"x-nested-path": true,
+	}
+}
+
+func dataAPIOperation(id, summary string, parameters []interface{}, body map[string]interface{}, responses map[string]interface{}) map[string]interface{} {
+	operation := map[string]interface{}{
+		"operationId": id,
+		"tags":        []string{"OPA REST API"},
+		"summary":     summary,
+		"responses":   responses,
+	}
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+	if body != nil {
+		operation["requestBody"] = map[string]interface{}{"content": body}
+	}
+	return operation
+}
+
+func dataAPIResponses(status, description string, schema map[string]interface{}) map[string]interface{} {
+	success := map[string]interface{}{"description": description}
+	if schema != nil {
+		success["content"] = jsonContent(schema)
+	}
+	errorResponse := map[string]interface{}{
+		"description": "Error",
+		"content":     jsonContent(schemaRef("DataAPIError")),
+	}
+	return map[string]interface{}{
+		status:    success,
+		"400":     errorResponse,
+		"404":     errorResponse,
+		"default": errorResponse,
+	}
+}
+
+// dataAPIPaths returns the paths of the OPA REST API, see dataAPI.
+func dataAPIPaths() map[string]interface{} {
+	evalParameters := []interface{}{
+		queryParameter("pretty", "indent the response"),
+		queryParameter("metrics", "return the metrics of the evaluation when true"),
+		queryParameter("instrument", "return the instrumentation metrics when true"),
+		queryParameter("explain", "return the trace of the evaluation: notes, fails or full"),
+	}
+	dataPath := nestedPathParameter("path", "Path of the document under data, a segment may be percent-encoded")
+
+	data := func(suffix string, parameters []interface{}) map[string]interface{} {
+		getParameters := append(append(append([]interface{}{}, parameters...), queryParameter("input", "JSON input document")), evalParameters...)
+		postParameters := append(append([]interface{}{}, parameters...), evalParameters...)
+		item := map[string]interface{}{
+			"get": dataAPIOperation("getData"+suffix, "Evaluate the document", getParameters, nil,
+				dataAPIResponses("200", "Value of the document, result is missing when undefined", schemaRef("DataResponse"))),
+			"post": dataAPIOperation("postData"+suffix, "Evaluate the document with an input", postParameters,
+				jsonContent(schemaRef("DataRequest")),
+				dataAPIResponses("200", "Value of the document, result is missing when undefined", schemaRef("DataResponse"))),
+			"put": dataAPIOperation("putData"+suffix, "Create or replace the document", parameters,
+				jsonContent(map[string]interface{}{}),
+				dataAPIResponses("204", "Document written", nil)),
+			"patch": dataAPIOperation("patchData"+suffix, "Apply a JSON Patch (add, remove, replace) to the document", parameters,
+				jsonContent(map[string]interface{}{"type": "array", "items": schemaRef("DataPatch")}),
+				dataAPIResponses("204", "Document patched", nil)),
+		}
+		if len(parameters) > 0 {
+			item["delete"] = dataAPIOperation("deleteData"+suffix, "Remove the document", parameters, nil,
+				dataAPIResponses("204", "Document removed", nil))
+		}
+		return item
+	}
+
+	policyID := []interface{}{nestedPathParameter("id", "Id of the policy")}
+
+	return map[string]interface{}{
+		"/v1/data":        data("Root", nil),
+		"/v1/data/{path}": data("", []interface{}{dataPath}),
+		"/v1/policies": map[string]interface{}{
+			"get": dataAPIOperation("listPolicies", "List the policies", nil, nil,
+				dataAPIResponses("200", "Policies", schemaRef("PoliciesResponse"))),
+		},
+		"/v1/policies/{id}": map[string]interface{}{
+			"get": dataAPIOperation("getPolicy", "Get the policy", policyID, nil,
+				dataAPIResponses("200", "Policy", schemaRef("PolicyResponse"))),
+			"put": dataAPIOperation("putPolicy", "Create or replace the policy", policyID,
+				map[string]interface{}{"text/plain": map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}},
+				dataAPIResponses("200", "Policy compiled and stored", map[string]interface{}{"type": "object"})),
+			"delete": dataAPIOperation("deletePolicy", "Remove the policy", policyID, nil,
+				dataAPIResponses("200", "Policy removed", map[string]interface{}{"type": "object"})),
+		},
+		"/v1/query": map[string]interface{}{
+			"get": dataAPIOperation("getQuery", "Evaluate an ad-hoc query",
+				append([]interface{}{queryParameter("q", "Rego query")}, evalParameters...), nil,
+				dataAPIResponses("200", "Bindings of the results", schemaRef("QueryResponse"))),
+			"post": dataAPIOperation("postQuery", "Evaluate an ad-hoc query with an input", evalParameters,
+				jsonContent(schemaRef("QueryRequest")),
+				dataAPIResponses("200", "Bindings of the results", schemaRef("QueryResponse"))),
+		},
+	}
+}
+
+// dataAPISchemas returns the schemas of the OPA REST API bodies.
+func dataAPISchemas() map[string]interface{} {
+	object := func(properties map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"type": "object", "properties": properties}
+	}
+	anyValue := map[string]interface{}{}
+	metrics := map[string]interface{}{"type": "object", "additionalProperties": true}
+	explanation := map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "object"}}
+	policy := object(map[string]interface{}{
+		"id":  map[string]interface{}{"type": "string"},
+		"raw": map[string]interface{}{"type": "string"},
+		"ast": map[string]interface{}{"type": "object"},
+	})
+
+	return map[string]interface{}{
+		"DataRequest": object(map[string]interface{}{
+			"input": anyValue,
+		}),
+		"DataResponse": object(map[string]interface{}{
+			"result":      anyValue,
+			"metrics":     metrics,
+			"explanation": explanation,
+		}),
+		"DataPatch": object(map[string]interface{}{
+			"op":    map[string]interface{}{"type": "string", "enum": []string{"add", "remove", "replace"}},
+			"path":  map[string]interface{}{"type": "string"},
+			"value": anyValue,
+		}),
+		"QueryRequest": object(map[string]interface{}{
+			"query": map[string]interface{}{"type": "string"},
+			"input": anyValue,
+		}),
+		"QueryResponse": object(map[string]interface{}{
+			"result":      map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "object", "additionalProperties": true}},
+			"metrics":     metrics,
+			"explanation": explanation,
+		}),
+		"Policy": policy,
+		"PolicyResponse": object(map[string]interface{}{
+			"result": schemaRef("Policy"),
+		}),
+		"PoliciesResponse": object(map[string]interface{}{
+			"result": map[string]interface{}{"type": "array", "items": schemaRef("Policy")},
+		}),
+		"DataAPIError": object(map[string]interface{}{
+			"code":    map[string]interface{}{"type": "string"},
+			"message": map[string]interface{}{"type": "string"},
+			"errors":  map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "object"}},
+		}),
+	}
+}
+
+// dataSourceStatusSchema returns the schema of dataSourceStatus.
+func dataSourceStatusSchema() map[string]interface{} {
+	str := map[string]interface{}{"type": "string"}
+	dateTime := map[string]interface{}{"type": "string", "format": "date-time"}
+	count := map[string]interface{}{"type": "integer", "format": "int64"}
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"name":               str,
+			"url":                str,
+			"path":               str,
+			"etag":               str,
+			"last_request":       dateTime,
+			"last_success":       dateTime,
+			"last_error":         dateTime,
+			"last_error_message": str,
+			"updates":            count,
+			"not_modified":       count,
+			"errors":             count,
+			"healthy":            map[string]interface{}{"type": "boolean"},
+		},
+	}
+}
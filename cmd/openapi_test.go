@@ -0,0 +1,82 @@
+// Copyright 2023 Honyrik.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestDocsPage(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newRestHandler(defaultDocsPath).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, defaultDocsPath, nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 but got %d", rec.Code)
+	}
+	page := rec.Body.String()
+	if strings.Contains(page, "{{") {
+		t.Fatal("expected every placeholder of the docs page to be replaced")
+	}
+	for _, expected := range []string{
+		`<a href="/openapi.json">/openapi.json</a>`,
+		`var openapi = "/openapi.json";`,
+	} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("expected the docs page to contain %s", expected)
+		}
+	}
+}
+
+// openAPIRoutePath is the echo wildcard of the nested paths, documented as
+// the {path} and {id} parameters.
+var openAPIRoutePath = regexp.MustCompile(`/\*$`)
+
+func TestOpenAPIDocumentsRoutes(t *testing.T) {
+	doc := newOpenAPIDocument()
+	paths := doc["paths"].(map[string]interface{})
+
+	for _, route := range newRestHandler(defaultDocsPath).Routes() {
+		if route.Path == defaultDocsPath || route.Path == openAPIPath {
+			continue
+		}
+		path := route.Path
+		if openAPIRoutePath.MatchString(path) {
+			if strings.HasPrefix(path, "/v1/policies/") {
+				path = openAPIRoutePath.ReplaceAllString(path, "/{id}")
+			} else {
+				path = openAPIRoutePath.ReplaceAllString(path, "/{path}")
+			}
+		}
+		item, ok := paths[path].(map[string]interface{})
+		if !ok {
+			t.Errorf("expected the route %s %s to be documented", route.Method, route.Path)
+			continue
+		}
+		if _, ok := item[strings.ToLower(route.Method)]; !ok {
+			t.Errorf("expected the method %s of %s to be documented", route.Method, path)
+		}
+	}
+
+	status := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})["DataSourceStatus"].(map[string]interface{})
+	if _, ok := status["properties"].(map[string]interface{})["healthy"]; !ok {
+		t.Error("expected the healthy property of DataSourceStatus")
+	}
+}
+
+func TestOpenAPINestedPaths(t *testing.T) {
+	paths := newOpenAPIDocument()["paths"].(map[string]interface{})
+
+	for path, name := range map[string]string{"/v1/data/{path}": "path", "/v1/policies/{id}": "id"} {
+		operation := paths[path].(map[string]interface{})["get"].(map[string]interface{})
+		parameter := operation["parameters"].([]interface{})[0].(map[string]interface{})
+		if parameter["name"] != name || parameter["x-nested-path"] != true {
+			t.Errorf("expected %s to be a nested path parameter of %s but got %v", name, path, parameter)
+		}
+	}
+}
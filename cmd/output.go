@@ -0,0 +1,117 @@
+// Copyright 2023 Honyrik.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	myUtil "github.com/Honyrik/opa-go-service/util"
+	"github.com/ghodss/yaml"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+const (
+	evalFormatJSON     = "json"
+	evalFormatPretty   = "pretty"
+	evalFormatRaw      = "raw"
+	evalFormatValues   = "values"
+	evalFormatBindings = "bindings"
+	evalFormatYAML     = "yaml"
+)
+
+var evalFormats = []string{evalFormatJSON, evalFormatPretty, evalFormatRaw, evalFormatValues, evalFormatBindings, evalFormatYAML}
+
+func printFormatted(w io.Writer, result rego.ResultSet, format string) error {
+	switch format {
+	case evalFormatPretty:
+		return printPretty(w, result)
+	case evalFormatRaw:
+		return printRaw(w, result)
+	case evalFormatValues:
+		return printJSON(w, myUtil.ResultSetValues(result))
+	case evalFormatBindings:
+		return printJSON(w, myUtil.ResultSetBindings(result))
+	case evalFormatYAML:
+		bs, err := yaml.Marshal(myUtil.ResultSetTArrayMap(result))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(bs)
+		return err
+	}
+	return printJSON(w, myUtil.ResultSetTArrayMap(result))
+}
+
+func printJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// printRaw prints the value of the first expression of the first result,
+// strings without quotes. Undefined results print nothing.
+func printRaw(w io.Writer, result rego.ResultSet) error {
+	if len(result) == 0 || len(result[0].Expressions) == 0 {
+		return nil
+	}
+	value := result[0].Expressions[0].Value
+	if s, ok := value.(string); ok {
+		_, err := fmt.Fprintln(w, s)
+		return err
+	}
+	bs, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(bs))
+	return err
+}
+
+// printPretty prints one row per result. Columns are the bindings when the
+// query has variables, the expressions otherwise.
+func printPretty(w io.Writer, result rego.ResultSet) error {
+	if len(result) == 0 {
+		_, err := fmt.Fprintln(w, "undefined")
+		return err
+	}
+
+	var header []string
+	for name := range result[0].Bindings {
+		header = append(header, name)
+	}
+	sort.Strings(header)
+	withBindings := len(header) > 0
+	if !withBindings {
+		for _, expression := range result[0].Expressions {
+			header = append(header, expression.Text)
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, elementResult := range result {
+		var row []string
+		for index, name := range header {
+			var value interface{}
+			if withBindings {
+				value = elementResult.Bindings[name]
+			} else if index < len(elementResult.Expressions) {
+				value = elementResult.Expressions[index].Value
+			}
+			bs, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			row = append(row, string(bs))
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
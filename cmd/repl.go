@@ -0,0 +1,318 @@
+// Copyright 2023 Honyrik.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/repl"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/util"
+	opaversion "github.com/open-policy-agent/opa/version"
+	"github.com/peterh/liner"
+	"github.com/spf13/cobra"
+)
+
+const (
+	replFormatPretty = "pretty"
+
+	replPrompt       = "> "
+	replBufferPrompt = "| "
+	replErrorLimit   = 10
+
+	replCommandInput = "input-file"
+)
+
+var replFormats = []string{replFormatPretty, evalFormatJSON}
+
+// replCommands are the commands of the OPA REPL offered by the completion.
+var replCommands = []string{
+	"show", "unset", "unset-package", "json", "pretty", "pretty-limit",
+	"trace", "notes", "fails", "metrics", "instrument", "profile", "types",
+	"unknown", "strict-builtin-errors", "dump", "help", "exit",
+	"package", "import", replCommandInput,
+}
+
+// replInputPath is the document the OPA REPL reads the input from.
+var replInputPath = storage.Path{"repl", "input"}
+
+type replCommandParams struct {
+	dataPaths   repeatedStringFlag
+	inputPath   string
+	historyPath string
+	format      *util.EnumFlag
+}
+
+func init() {
+
+	params := replCommandParams{
+		format: util.NewEnumFlag(replFormatPretty, replFormats),
+	}
+
+	replCommand := &cobra.Command{
+		Use:   "repl",
+		Short: "Start an interactive Rego shell",
+		Long: `Start an interactive shell to evaluate Rego queries.
+
+The shell loads the policy and data files like eval. Queries are evaluated
+against them, rules entered at the prompt are kept in a temporary module, and
+"trace" and "metrics" toggle the explanations and the metrics of the
+evaluations. "input-file <file>" sets the input document from a JSON or YAML
+file, "help" lists the other commands. Tab completes the packages and the rules.
+
+Examples
+--------
+
+To start a shell on a policy directory with an input document:
+
+    $ opa-go-service repl --data policy/ --input input.json
+`,
+
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runRepl(context.Background(), params, os.Stdout); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	addDataFlag(replCommand.Flags(), &params.dataPaths)
+	addInputFlag(replCommand.Flags(), &params.inputPath)
+	addOutputFormatFlag(replCommand.Flags(), params.format)
+	replCommand.Flags().StringVarP(&params.historyPath, "history", "", defaultReplHistoryPath(), "set path of the history file")
+
+	RootCommand.AddCommand(replCommand)
+}
+
+func defaultReplHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".opa_history"
+	}
+	return filepath.Join(home, ".opa_history")
+}
+
+// replShell runs the prompt of the OPA REPL with the input command and a
+// completion of the packages and the rules, including the temporary rules
+// entered at the prompt.
+type replShell struct {
+	repl   *repl.REPL
+	store  storage.Store
+	output io.Writer
+
+	buffering bool
+	pkg       ast.Ref
+	rules     map[string]bool
+}
+
+func runRepl(ctx context.Context, params replCommandParams, w io.Writer) error {
+	store := inmem.New()
+	if err := loadStorage(ctx, store, params.dataPaths.v); err != nil {
+		return err
+	}
+
+	s := &replShell{
+		store:  store,
+		output: w,
+		pkg:    ast.MustParseRef("data.repl"),
+		rules:  map[string]bool{},
+	}
+	if params.inputPath != "" {
+		if err := s.setInput(ctx, params.inputPath); err != nil {
+			return err
+		}
+	}
+
+	s.repl = repl.New(store, params.historyPath, w, params.format.String(), replErrorLimit, "")
+
+	return s.loop(ctx, params.historyPath)
+}
+
+func (s *replShell) loop(ctx context.Context, historyPath string) error {
+	line := liner.NewLiner()
+	defer line.Close()
+	line.SetCtrlCAborts(true)
+	line.SetMultiLineMode(true)
+	line.SetWordCompleter(s.complete)
+
+	if f, err := os.Open(historyPath); err == nil {
+		line.ReadHistory(f)
+		f.Close()
+	}
+	defer func() {
+		if f, err := os.Create(historyPath); err == nil {
+			line.WriteHistory(f)
+			f.Close()
+		}
+	}()
+
+	fmt.Fprintf(s.output, "%s %s (OPA %s). Run 'help' to see a list of commands.\n", RootCommand.Name(), version, opaversion.Version)
+
+	for {
+		prompt := replPrompt
+		if s.buffering {
+			prompt = replBufferPrompt
+		}
+		input, err := line.Prompt(prompt)
+		if err == io.EOF {
+			fmt.Fprintln(s.output)
+			return nil
+		}
+		if err == liner.ErrPromptAborted {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		line.AppendHistory(input)
+
+		if exit, err := s.eval(ctx, input); err != nil {
+			fmt.Fprintln(s.output, err)
+		} else if exit {
+			return nil
+		}
+	}
+}
+
+// eval runs the line in the REPL, the input-file command is handled here. A
+// line is the command only with exactly a file after it, anything else such
+// as "input-file" alone is left to the REPL as Rego. It reports whether the
+// line exits the shell.
+func (s *replShell) eval(ctx context.Context, input string) (bool, error) {
+	fields := strings.Fields(input)
+	if !s.buffering && len(fields) > 0 {
+		switch {
+		case fields[0] == replCommandInput && len(fields) == 2:
+			return false, s.setInput(ctx, fields[1])
+		case fields[0] == "exit":
+			return true, nil
+		}
+	}
+
+	if err := s.repl.OneShot(ctx, input); err != nil {
+		s.buffering = false
+		return false, err
+	}
+
+	if len(fields) > 0 && fields[0] == "help" && len(fields) == 1 {
+		fmt.Fprintf(s.output, "%s <file>  set the input document from a JSON or YAML file\n\n", replCommandInput)
+	}
+	s.track(input)
+	return false, nil
+}
+
+// track follows the statements the REPL accepted: the lines it buffers until
+// an empty line, the active package and the temporary rules.
+func (s *replShell) track(input string) {
+	if s.buffering {
+		s.buffering = strings.TrimSpace(input) != ""
+		return
+	}
+	if strings.TrimSpace(input) == "" {
+		return
+	}
+	stmts, _, err := ast.ParseStatementsWithOpts("", input, ast.ParserOptions{AllFutureKeywords: true})
+	if err != nil {
+		s.buffering = true
+		return
+	}
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *ast.Package:
+			s.pkg = stmt.Path
+		case *ast.Rule:
+			s.rules[s.pkg.Append(ast.StringTerm(string(stmt.Head.Name))).String()] = true
+		case ast.Body:
+			if len(stmt) != 1 || !(stmt[0].IsAssignment() || stmt[0].IsEquality()) {
+				continue
+			}
+			if v, ok := stmt[0].Operand(0).Value.(ast.Var); ok && !v.IsWildcard() {
+				s.rules[s.pkg.Append(ast.StringTerm(string(v))).String()] = true
+			}
+		}
+	}
+}
+
+// setInput writes the document of the file to data.repl.input.
+func (s *replShell) setInput(ctx context.Context, file string) error {
+	bs, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var input interface{}
+	if err := util.Unmarshal(bs, &input); err != nil {
+		return fmt.Errorf("unable to parse input: %v", err)
+	}
+	return storage.Txn(ctx, s.store, storage.WriteParams, func(txn storage.Transaction) error {
+		return writeDocument(ctx, s.store, txn, replInputPath, input)
+	})
+}
+
+// complete completes the word under the cursor with the commands at the
+// start of the line, and with the packages and the rules of the policies
+// and of the temporary module.
+func (s *replShell) complete(line string, pos int) (string, []string, string) {
+	runes := []rune(line)
+	head, tail := string(runes[:pos]), string(runes[pos:])
+	start := strings.LastIndexAny(head, " \t()[]{},=!<>+-*/|&:;") + 1
+	word := head[start:]
+
+	candidates := map[string]bool{}
+	if strings.TrimSpace(head[:start]) == "" {
+		for _, command := range replCommands {
+			candidates[command] = true
+		}
+	}
+	for path := range s.rules {
+		candidates[path] = true
+	}
+	for _, module := range s.modules() {
+		candidates[module.Package.Path.String()] = true
+		for _, rule := range module.Rules {
+			candidates[rule.Path().String()] = true
+		}
+	}
+
+	var res []string
+	for candidate := range candidates {
+		if strings.HasPrefix(candidate, word) {
+			res = append(res, candidate)
+		}
+	}
+	sort.Strings(res)
+	return head[:start], res, tail
+}
+
+// modules parses the policies of the store.
+func (s *replShell) modules() []*ast.Module {
+	var res []*ast.Module
+	ctx := context.Background()
+	storage.Txn(ctx, s.store, storage.TransactionParams{}, func(txn storage.Transaction) error {
+		ids, err := s.store.ListPolicies(ctx, txn)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			bs, err := s.store.GetPolicy(ctx, txn, id)
+			if err != nil {
+				return err
+			}
+			if module, err := ast.ParseModule(id, string(bs)); err == nil {
+				res = append(res, module)
+			}
+		}
+		return nil
+	})
+	return res
+}
@@ -18,24 +18,35 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	pb "github.com/Honyrik/opa-go-service/grpc"
 	myUtil "github.com/Honyrik/opa-go-service/util"
 	"github.com/labstack/echo"
 	"github.com/labstack/echo/middleware"
+	"github.com/open-policy-agent/opa/cover"
+	"github.com/open-policy-agent/opa/metrics"
+	"github.com/open-policy-agent/opa/profiler"
 	"github.com/open-policy-agent/opa/rego"
 	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/topdown"
 	"github.com/open-policy-agent/opa/util"
 	"github.com/spf13/cobra"
 	"google.golang.org/grpc"
-	"k8s.io/client-go/util/jsonpath"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 )
 
 type serverCommandParams struct {
 	restPort   string
 	grpcPort   string
 	probesPort string
+	docsPath   string
+	storage    storageParams
+	sources    string
+	maxTest    time.Duration
 }
 
 type server struct {
@@ -43,12 +54,16 @@ type server struct {
 }
 
 var cachePrepare = make(map[string]rego.PreparedEvalQuery)
+var cachePrepareMu sync.RWMutex
 
-func getPreparedEvalQuery(ctx context.Context, in *pb.ApiRequest) (rego.PreparedEvalQuery, error) {
+// maxProfileAggregators bounds the aggregated profiles, the profiles of the
+// least recently profiled queries are dropped first.
+const maxProfileAggregators = 100
+
+var profilePrepare = myUtil.NewLRU(maxProfileAggregators)
+
+func preparedQueryKey(in *pb.ApiRequest) string {
 	var strArray []string
-	if in.Query == "" {
-		return rego.PreparedEvalQuery{}, fmt.Errorf("Need query")
-	}
 
 	if in.Data != "" {
 		strArray = append(strArray, in.Data)
@@ -61,8 +76,52 @@ func getPreparedEvalQuery(ctx context.Context, in *pb.ApiRequest) (rego.Prepared
 	gob.NewEncoder(buf).Encode(strArray)
 	md5SumBuf := md5.Sum(buf.Bytes())
 
-	md5Sum := string(md5SumBuf[:])
+	return string(md5SumBuf[:])
+}
+
+func getProfileAggregator(key string) *myUtil.ProfileAggregator {
+	return profilePrepare.GetOrAdd(key, func() interface{} {
+		return myUtil.NewProfileAggregator()
+	}).(*myUtil.ProfileAggregator)
+}
+
+func policyRegoArgs(data string, packages []string) ([]func(*rego.Rego), error) {
+	var regoArgs []func(*rego.Rego)
+
+	if data != "" {
+		var value map[string]interface{}
+		err := util.Unmarshal([]byte(data), &value)
+		if err != nil {
+			return nil, err
+		}
+		store := inmem.NewFromObject(value)
+		regoArgs = append(regoArgs, rego.Store(store))
+	}
+
+	for index, module := range packages {
+		regoArgs = append(regoArgs, rego.Module(fmt.Sprintf("rego_%d.rego", index), module))
+	}
+
+	return regoArgs, nil
+}
+
+func getPreparedEvalQuery(ctx context.Context, in *pb.ApiRequest, m metrics.Metrics) (rego.PreparedEvalQuery, error) {
+	if in.Query == "" {
+		return rego.PreparedEvalQuery{}, fmt.Errorf("Need query")
+	}
+
+	md5Sum := preparedQueryKey(in)
+	cachePrepareMu.RLock()
 	pq, exist := cachePrepare[md5Sum]
+	cachePrepareMu.RUnlock()
+
+	if in.IsCache && m != nil {
+		if exist {
+			m.Counter("server_query_cache_hit").Incr()
+		} else {
+			m.Counter("server_query_cache_miss").Incr()
+		}
+	}
 
 	if exist && in.IsCache {
 		return pq, nil
@@ -70,21 +129,15 @@ func getPreparedEvalQuery(ctx context.Context, in *pb.ApiRequest) (rego.Prepared
 
 	regoArgs := []func(*rego.Rego){rego.Query(in.Query)}
 
-	if in.Data != "" {
-		var data map[string]interface{}
-		err := util.Unmarshal([]byte(in.Data), &data)
-		if err != nil {
-			return pq, err
-		}
-		store := inmem.NewFromObject(data)
-		regoArgs = append(regoArgs, rego.Store(store))
+	if m != nil {
+		regoArgs = append(regoArgs, rego.Metrics(m), rego.Instrument(true))
 	}
 
-	if len(in.Packages) > 0 {
-		for index, data := range in.Packages {
-			regoArgs = append(regoArgs, rego.Module(fmt.Sprint("rego_%d.rego", index), data))
-		}
+	policyArgs, err := policyRegoArgs(in.Data, in.Packages)
+	if err != nil {
+		return pq, err
 	}
+	regoArgs = append(regoArgs, policyArgs...)
 
 	r := rego.New(regoArgs...)
 
@@ -94,18 +147,59 @@ func getPreparedEvalQuery(ctx context.Context, in *pb.ApiRequest) (rego.Prepared
 	}
 
 	if in.IsCache {
+		cachePrepareMu.Lock()
 		cachePrepare[md5Sum] = pq
+		cachePrepareMu.Unlock()
 	}
 	return pq, nil
 }
 
 func ExecuteRego(ctx context.Context, in *pb.ApiRequest) (*pb.ApiResult, error) {
-	pq, errPq := getPreparedEvalQuery(ctx, in)
+	if err := myUtil.ValidateExplain(in.Explain); err != nil {
+		return &pb.ApiResult{
+			IsSuccess: false,
+			Error:     err.Error(),
+		}, nil
+	}
+
+	if err := myUtil.ValidateResultPathLanguage(in.ResultPathLanguage); err != nil {
+		return &pb.ApiResult{
+			IsSuccess: false,
+			Error:     err.Error(),
+		}, nil
+	}
+
+	if err := myUtil.ValidateProfileSort(in.ProfileSort); err != nil {
+		return &pb.ApiResult{
+			IsSuccess: false,
+			Error:     err.Error(),
+		}, nil
+	}
+
+	if err := myUtil.ValidateInputFormat(in.InputFormat); err != nil {
+		return &pb.ApiResult{
+			IsSuccess: false,
+			Error:     err.Error(),
+		}, nil
+	}
+
+	var m metrics.Metrics
+	if in.Instrument {
+		m = metrics.New()
+	}
+
+	if m != nil {
+		m.Timer("server_query_prepare").Start()
+	}
+	pq, errPq := getPreparedEvalQuery(ctx, in, m)
+	if m != nil {
+		m.Timer("server_query_prepare").Stop()
+	}
 
 	if errPq != nil {
 		return &pb.ApiResult{
 			IsSuccess: false,
-			Error:     fmt.Sprint("unable to prepare query: %v", errPq),
+			Error:     fmt.Sprintf("unable to prepare query: %v", errPq),
 		}, nil
 	}
 
@@ -114,64 +208,149 @@ func ExecuteRego(ctx context.Context, in *pb.ApiRequest) (*pb.ApiResult, error)
 		rego.EvalEarlyExit(true),
 	}
 
+	if m != nil {
+		evalArgs = append(evalArgs, rego.EvalMetrics(m), rego.EvalInstrument(true))
+	}
+
 	if in.Input != "" {
-		var input interface{}
-		err := util.Unmarshal([]byte(in.Input), &input)
+		if m != nil {
+			m.Timer("server_input_parse").Start()
+		}
+		input, err := myUtil.ParseInput("input", []byte(in.Input), in.InputFormat)
+		if m != nil {
+			m.Timer("server_input_parse").Stop()
+		}
 		if err != nil {
 			return &pb.ApiResult{
 				IsSuccess: false,
-				Error:     fmt.Sprint("unable to parse input: %v", err),
+				Error:     fmt.Sprintf("unable to parse input: %v", err),
 			}, nil
 		}
 		evalArgs = append(evalArgs, rego.EvalInput(input))
 	}
+
+	var buf *topdown.BufferTracer
+	if myUtil.IsExplain(in.Explain) {
+		buf = topdown.NewBufferTracer()
+		evalArgs = append(evalArgs, rego.EvalQueryTracer(buf))
+	}
+
+	var prof *profiler.Profiler
+	if in.Profile || in.ProfileAggregate {
+		prof = profiler.New()
+		evalArgs = append(evalArgs, rego.EvalQueryTracer(prof))
+	}
+
+	var cov *cover.Cover
+	if in.Coverage {
+		cov = cover.New()
+		evalArgs = append(evalArgs, rego.EvalQueryTracer(cov))
+	}
+
 	result, resultErr := pq.Eval(ctx, evalArgs...)
 	if resultErr != nil {
 		return &pb.ApiResult{
 			IsSuccess: false,
-			Error:     fmt.Sprint("Unable Eval: %v", resultErr),
+			Error:     fmt.Sprintf("Unable Eval: %v", resultErr),
 		}, nil
 	}
 
-	if in.ResultPath == "" {
-		res := myUtil.ResultSetTArrayMap(result)
-		resJson, resJsonErr := json.Marshal(res)
-		if resJsonErr != nil {
+	if m != nil {
+		m.Timer("server_result_projection").Start()
+	}
+	out, errOut := resultString(ctx, result, in)
+	if m != nil {
+		m.Timer("server_result_projection").Stop()
+	}
+	if errOut != nil {
+		return &pb.ApiResult{
+			IsSuccess: false,
+			Error:     errOut.Error(),
+		}, nil
+	}
+
+	res := &pb.ApiResult{
+		IsSuccess: true,
+		Result:    out,
+	}
+
+	if buf != nil {
+		trace := myUtil.FilterTrace(*buf, in.Explain)
+		traceJson, traceErr := myUtil.TraceJSON(trace)
+		if traceErr != nil {
 			return &pb.ApiResult{
 				IsSuccess: false,
-				Error:     fmt.Sprint("Unable Json: %v", resJsonErr),
+				Error:     fmt.Sprintf("Unable Json Trace: %v", traceErr),
 			}, nil
 		}
-		return &pb.ApiResult{
-			IsSuccess: true,
-			Result:    string(resJson[:]),
-		}, nil
+		res.Explanation = myUtil.PrettyTrace(trace)
+		res.Trace = traceJson
 	}
 
-	parse := jsonpath.New("")
-	parse.EnableJSONOutput(false)
-	resultPathErr := parse.Parse(in.ResultPath)
-	if resultPathErr != nil {
-		return &pb.ApiResult{
-			IsSuccess: false,
-			Error:     fmt.Sprint("Unable Prepare Result Path: %v", resultPathErr),
-		}, nil
+	if prof != nil {
+		stats := prof.ReportTopNResults(0, nil)
+		if in.ProfileAggregate {
+			aggregator := getProfileAggregator(preparedQueryKey(in))
+			aggregator.Add(stats)
+			stats = aggregator.Stats()
+		}
+		stats = myUtil.SortProfile(stats, in.ProfileSort, int(in.ProfileLimit))
+		profileJson, profileErr := json.Marshal(stats)
+		if profileErr != nil {
+			return &pb.ApiResult{
+				IsSuccess: false,
+				Error:     fmt.Sprintf("Unable Json Profile: %v", profileErr),
+			}, nil
+		}
+		res.Profile = string(profileJson[:])
+	}
+
+	if m != nil {
+		metricsJson, metricsErr := json.Marshal(m.All())
+		if metricsErr != nil {
+			return &pb.ApiResult{
+				IsSuccess: false,
+				Error:     fmt.Sprintf("Unable Json Metrics: %v", metricsErr),
+			}, nil
+		}
+		res.Metrics = string(metricsJson[:])
+	}
+
+	if cov != nil {
+		coverageJson, coverageErr := json.Marshal(cov.Report(coverageModules(pq.Modules())))
+		if coverageErr != nil {
+			return &pb.ApiResult{
+				IsSuccess: false,
+				Error:     fmt.Sprintf("Unable Json Coverage: %v", coverageErr),
+			}, nil
+		}
+		res.Coverage = string(coverageJson[:])
+	}
+
+	return res, nil
+}
+
+func resultString(ctx context.Context, result rego.ResultSet, in *pb.ApiRequest) (string, error) {
+	res, resErr := myUtil.ResultSetFormat(result, in.ResultFormat)
+	if resErr != nil {
+		return "", resErr
+	}
+
+	if in.ResultPath == "" {
+		resJson, resJsonErr := json.Marshal(res)
+		if resJsonErr != nil {
+			return "", fmt.Errorf("Unable Json: %v", resJsonErr)
+		}
+		return string(resJson[:]), nil
 	}
 
-	res := myUtil.ResultSetTArrayMap(result)
 	w := new(strings.Builder)
-	printErr := parse.Execute(w, res)
-	if printErr != nil {
-		return &pb.ApiResult{
-			IsSuccess: false,
-			Error:     fmt.Sprint("Unable Find Result Path: ", printErr),
-		}, nil
+	err := myUtil.ExecuteResultPath(ctx, w, res, in.ResultPath, in.ResultPathLanguage)
+	if err != nil {
+		return "", err
 	}
 
-	return &pb.ApiResult{
-		IsSuccess: true,
-		Result:    w.String(),
-	}, nil
+	return w.String(), nil
 }
 
 func (s *server) Execute(ctx context.Context, in *pb.ApiRequest) (*pb.ApiResult, error) {
@@ -184,7 +363,7 @@ func Execute(c echo.Context) error {
 	if err != nil {
 		c.JSON(http.StatusOK, &pb.ApiResult{
 			IsSuccess: false,
-			Error:     fmt.Sprint("Unable Post Data: %v", err),
+			Error:     fmt.Sprintf("Unable Post Data: %v", err),
 		})
 		return nil
 	}
@@ -193,7 +372,7 @@ func Execute(c echo.Context) error {
 	if err != nil {
 		c.JSON(http.StatusOK, &pb.ApiResult{
 			IsSuccess: false,
-			Error:     fmt.Sprint("Unable Execute Rego: %v", err),
+			Error:     fmt.Sprintf("Unable Execute Rego: %v", err),
 		})
 		return nil
 	}
@@ -203,9 +382,52 @@ func Execute(c echo.Context) error {
 }
 
 var isReady = true
+var isReadyMu sync.RWMutex
+
+// healthServer is the grpc.health.v1 service, it reports the readiness for
+// the whole server and for the Api service.
+var healthServer = newHealthServer()
+
+func newHealthServer() *health.Server {
+	s := health.NewServer()
+	s.SetServingStatus(pb.Api_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	return s
+}
+
+// setReady sets the readiness of the startup, the server is ready when the
+// data sources are healthy as well.
+func setReady(ready bool) {
+	isReadyMu.Lock()
+	isReady = ready
+	isReadyMu.Unlock()
+
+	refreshReady()
+}
+
+// refreshReady sets the readiness reported by the gRPC health service.
+func refreshReady() {
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if serverReady() {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+	healthServer.SetServingStatus("", status)
+	healthServer.SetServingStatus(pb.Api_ServiceDesc.ServiceName, status)
+}
+
+// serverReady reports the readiness of the readiness probe and the gRPC
+// health service.
+func serverReady() bool {
+	return getReady() && dataSourcesHealthy()
+}
+
+func getReady() bool {
+	isReadyMu.RLock()
+	defer isReadyMu.RUnlock()
+	return isReady
+}
 
 func Readiness(c echo.Context) error {
-	if isReady {
+	if serverReady() {
 		c.String(http.StatusOK, "Ready!")
 	} else {
 		c.String(http.StatusBadRequest, "Not ready!")
@@ -246,6 +468,14 @@ func init() {
 	evalCommand.Flags().StringVarP(&params.grpcPort, "grpc-port", "g", os.Getenv("GRPC_PORT"), "gRPC port")
 	evalCommand.Flags().StringVarP(&params.restPort, "rest-port", "r", os.Getenv("REST_PORT"), "REST port")
 	evalCommand.Flags().StringVarP(&params.probesPort, "probes-port", "p", os.Getenv("PROBE_PORT"), "PROBE port")
+	evalCommand.Flags().StringVarP(&params.docsPath, "docs-path", "", os.Getenv("DOCS_PATH"), "REST API docs path (default /docs)")
+	evalCommand.Flags().StringVarP(&params.storage.backend, "storage", "", os.Getenv("STORAGE"), fmt.Sprintf("storage of the Data API: %s (default %s)", strings.Join(storageBackends, ", "), storageInmem))
+	evalCommand.Flags().StringVarP(&params.storage.dir, "storage-dir", "", os.Getenv("STORAGE_DIR"), "directory of the disk storage")
+	evalCommand.Flags().VarP(&params.storage.partitions, "storage-partition", "", "path partitioning the disk storage, its children are stored as separate keys, such as /entitlements. This flag can be repeated.")
+	evalCommand.Flags().StringVarP(&params.storage.badger, "storage-badger", "", os.Getenv("STORAGE_BADGER"), "badger options of the disk storage (default "+defaultBadgerOptions+")")
+	evalCommand.Flags().VarP(&params.storage.load, "storage-load", "", "data or policy file(s) written into the storage at startup. This flag can be repeated.")
+	evalCommand.Flags().StringVarP(&params.sources, "data-sources", "", os.Getenv("DATA_SOURCES"), "YAML or JSON file of the HTTP sources polled into the storage")
+	evalCommand.Flags().DurationVarP(&params.maxTest, "test-timeout-max", "", maxTestTimeout(), "maximum timeout of the tests run by /test")
 	RootCommand.AddCommand(evalCommand)
 }
 
@@ -279,6 +509,19 @@ func connectionTimeout() time.Duration {
 	return timeout
 }
 
+func maxTestTimeout() time.Duration {
+	if os.Getenv("TEST_TIMEOUT_MAX") != "" {
+		i, err := time.ParseDuration(os.Getenv("TEST_TIMEOUT_MAX"))
+		if err != nil {
+			log.Println(err)
+		} else {
+			return i
+		}
+	}
+
+	return defaultMaxTestTimeout
+}
+
 func startProbes(port string, errChan chan error) {
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", port))
 	if err != nil {
@@ -300,18 +543,50 @@ func startProbes(port string, errChan chan error) {
 	}
 }
 
-func startRest(port string, errChan chan error) {
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", port))
-	if err != nil {
-		errChan <- fmt.Errorf("failed to listen: %v", err)
-	}
+// restRoute is a REST route of an Api method, the OpenAPI document is
+// generated from the method of the proto service.
+type restRoute struct {
+	path    string
+	method  string
+	handler echo.HandlerFunc
+}
+
+var restRoutes = []restRoute{
+	{path: "/execute", method: "Execute", handler: Execute},
+	{path: "/compile", method: "Compile", handler: Compile},
+	{path: "/check", method: "Check", handler: Check},
+	{path: "/format", method: "Format", handler: Format},
+	{path: "/test", method: "RunTests", handler: RunTests},
+}
+
+// NewRestHandler returns the handler of the REST routes with the API docs at
+// /docs, it can be served in process with net/http/httptest.
+func NewRestHandler() *echo.Echo {
+	return newRestHandler(defaultDocsPath)
+}
+
+func newRestHandler(docsPath string) *echo.Echo {
 	mux := echo.New()
 	mux.Use(
 		middleware.Logger(),
 	)
-	mux.POST("/execute", Execute)
+	for _, route := range restRoutes {
+		mux.POST(route.path, route.handler)
+	}
+	mux.GET("/datasources", DataSources)
+	mux.GET("/metrics", Metrics)
+	serverDataAPI.register(mux)
+	registerOpenAPI(mux, docsPath)
+	return mux
+}
+
+func startRest(port string, docsPath string, errChan chan error) {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", port))
+	if err != nil {
+		errChan <- fmt.Errorf("failed to listen: %v", err)
+	}
 	s := http.Server{
-		Handler:        mux,
+		Handler:        newRestHandler(docsPath),
 		MaxHeaderBytes: maxMessageSize(),
 		ReadTimeout:    connectionTimeout(),
 		WriteTimeout:   connectionTimeout(),
@@ -322,19 +597,28 @@ func startRest(port string, errChan chan error) {
 	}
 }
 
-func startGrpc(port string, errChan chan error) {
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", port))
-	if err != nil {
-		errChan <- fmt.Errorf("failed to listen: %v", err)
-	}
-	var opts []grpc.ServerOption
-	opts = append(opts,
+// NewGrpcServer returns a gRPC server with the Api, health and reflection
+// services registered, it can be served in process on any listener such as
+// grpc/test/bufconn.
+func NewGrpcServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append([]grpc.ServerOption{
 		grpc.MaxMsgSize(maxMessageSize()),
 		grpc.ConnectionTimeout(connectionTimeout()),
-	)
+	}, opts...)
 	s := grpc.NewServer(opts...)
 
 	pb.RegisterApiServer(s, &server{})
+	healthpb.RegisterHealthServer(s, healthServer)
+	reflection.Register(s)
+	return s
+}
+
+func startGrpc(port string, errChan chan error) {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", port))
+	if err != nil {
+		errChan <- fmt.Errorf("failed to listen: %v", err)
+	}
+	s := NewGrpcServer()
 	log.Printf("server grpc listening at %v", lis.Addr())
 	if err := s.Serve(lis); err != nil {
 		errChan <- fmt.Errorf("failed to serve: %v", err)
@@ -354,18 +638,52 @@ func startServer(args []string, params serverCommandParams, w io.Writer) (bool,
 	if probesPort == "" {
 		probesPort = "10080"
 	}
+	if params.maxTest <= 0 {
+		return false, fmt.Errorf("specify --test-timeout-max greater than zero")
+	}
+	serverMaxTestTimeout = params.maxTest
 	errChan := make(chan error)
 
+	setReady(false)
 	go startGrpc(grpcPort, errChan)
-	go startRest(restPort, errChan)
 	go startProbes(probesPort, errChan)
 
+	ctx := context.Background()
+	store, err := openStorage(ctx, params.storage)
+	if err != nil {
+		return false, fmt.Errorf("unable to open storage: %v", err)
+	}
+	if err := loadStorage(ctx, store, params.storage.load.v); err != nil {
+		return false, fmt.Errorf("unable to load storage: %v", err)
+	}
+	if err := serverDataAPI.open(ctx, store); err != nil {
+		return false, fmt.Errorf("unable to compile stored policies: %v", err)
+	}
+
+	if params.sources != "" {
+		configs, err := readDataSourcesConfig(params.sources)
+		if err != nil {
+			return false, fmt.Errorf("unable to read data sources: %v", err)
+		}
+		serverDataSources, err = newDataSources(store, &http.Client{}, configs)
+		if err != nil {
+			return false, fmt.Errorf("unable to read data sources: %v", err)
+		}
+		serverDataSources.run(ctx)
+	}
+
+	docsPath := params.docsPath
+	if docsPath == "" {
+		docsPath = defaultDocsPath
+	}
+
+	go startRest(restPort, docsPath, errChan)
+	setReady(true)
+
 	for {
 		val, _ := <-errChan
 		if val != nil {
 			return false, val
 		}
 	}
-
-	return true, nil
 }
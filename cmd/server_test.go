@@ -0,0 +1,35 @@
+// Copyright 2023 Honyrik.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	pb "github.com/Honyrik/opa-go-service/grpc"
+)
+
+func TestInvalidInputFormat(t *testing.T) {
+	ctx := context.Background()
+	expected := `invalid input format "xml"`
+
+	// The format is checked even without an input.
+	res, err := ExecuteRego(ctx, &pb.ApiRequest{Query: "x := 1", InputFormat: "xml"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.IsSuccess || !strings.Contains(res.Error, expected) {
+		t.Fatalf("expected the error %s but got %+v", expected, res)
+	}
+
+	compiled, err := CompileRego(ctx, &pb.CompileRequest{Query: "x := 1", InputFormat: "xml"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if compiled.IsSuccess || !strings.Contains(compiled.Error, expected) {
+		t.Fatalf("expected the error %s but got %+v", expected, compiled)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2023 Honyrik.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/loader"
+	"github.com/open-policy-agent/opa/logging"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/disk"
+	"github.com/open-policy-agent/opa/storage/inmem"
+)
+
+const (
+	storageInmem = "inmem"
+	storageDisk  = "disk"
+
+	// defaultBadgerOptions syncs every commit to disk so a crash loses no
+	// acknowledged write.
+	defaultBadgerOptions = "syncwrites=true"
+)
+
+var storageBackends = []string{storageInmem, storageDisk}
+
+type storageParams struct {
+	backend    string
+	dir        string
+	partitions repeatedStringFlag
+	badger     string
+	load       repeatedStringFlag
+}
+
+// openStorage opens the store of the backend. The disk store keeps the data
+// and the policies in dir, the partitions lay out the documents under those
+// paths as one key per child for large datasets.
+func openStorage(ctx context.Context, params storageParams) (storage.Store, error) {
+	switch params.backend {
+	case "", storageInmem:
+		return inmem.New(), nil
+	case storageDisk:
+		if params.dir == "" {
+			return nil, fmt.Errorf("specify the storage directory for the %s storage", storageDisk)
+		}
+		var partitions []storage.Path
+		for _, partition := range params.partitions.v {
+			path, ok := storage.ParsePathEscaped(partition)
+			if !ok {
+				return nil, fmt.Errorf("invalid storage partition %q", partition)
+			}
+			partitions = append(partitions, path)
+		}
+		badger := params.badger
+		if badger == "" {
+			badger = defaultBadgerOptions
+		}
+		return disk.New(ctx, logging.New(), nil, disk.Options{
+			Dir:        params.dir,
+			Partitions: partitions,
+			Badger:     badger,
+		})
+	}
+	return nil, fmt.Errorf("invalid storage %q, expected one of: %v", params.backend, storageBackends)
+}
+
+// loadStorage writes the data documents and the policies of the files under
+// the paths into the store, the documents replace the top-level keys they
+// define.
+func loadStorage(ctx context.Context, store storage.Store, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	result, err := loader.NewFileLoader().Filtered(paths, nil)
+	if err != nil {
+		return err
+	}
+
+	var keys []string
+	for key := range result.Documents {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+		for _, key := range keys {
+			if err := writeDocument(ctx, store, txn, storage.Path{key}, result.Documents[key]); err != nil {
+				return err
+			}
+		}
+		for id, module := range result.Modules {
+			if err := store.UpsertPolicy(ctx, txn, id, module.Raw); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// open replaces the store of the Data API and compiles the policies it holds.
+func (d *dataAPI) open(ctx context.Context, store storage.Store) error {
+	modules := map[string]*ast.Module{}
+
+	err := storage.Txn(ctx, store, storage.TransactionParams{}, func(txn storage.Transaction) error {
+		ids, err := store.ListPolicies(ctx, txn)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			bs, err := store.GetPolicy(ctx, txn, id)
+			if err != nil {
+				return err
+			}
+			module, err := ast.ParseModuleWithOpts(id, string(bs), ast.ParserOptions{ProcessAnnotation: true})
+			if err != nil {
+				return err
+			}
+			modules[id] = module
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	compiler := ast.NewCompiler().WithEnablePrintStatements(true)
+	compiler.Compile(modules)
+	if compiler.Failed() {
+		return compiler.Errors
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.store = store
+	d.compiler = compiler
+	d.modules = modules
+	d.prepared.Purge()
+	log.Printf("storage loaded with %d policies", len(modules))
+	return nil
+}
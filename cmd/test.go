@@ -0,0 +1,444 @@
+// Copyright 2023 Honyrik.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	pb "github.com/Honyrik/opa-go-service/grpc"
+	myUtil "github.com/Honyrik/opa-go-service/util"
+	"github.com/labstack/echo"
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/cover"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/tester"
+	"github.com/open-policy-agent/opa/util"
+	"github.com/spf13/cobra"
+)
+
+const (
+	testStatusPass  = "pass"
+	testStatusFail  = "fail"
+	testStatusError = "error"
+	testStatusSkip  = "skip"
+
+	testFormatJUnit = "junit"
+
+	defaultTestTimeout    = 5 * time.Second
+	defaultMaxTestTimeout = time.Minute
+)
+
+// serverMaxTestTimeout caps the timeout of the tests run by the server.
+var serverMaxTestTimeout = defaultMaxTestTimeout
+
+type testCommandParams struct {
+	run       string
+	timeout   time.Duration
+	verbose   bool
+	format    *util.EnumFlag
+	coverage  bool
+	threshold float64
+}
+
+func runRegoTests(ctx context.Context, modules map[string]*ast.Module, store storage.Store, run string, timeout time.Duration, cov *cover.Cover) ([]*tester.Result, error) {
+	runner := tester.NewRunner().
+		SetCompiler(ast.NewCompiler().WithEnablePrintStatements(true)).
+		SetStore(store).
+		SetModules(modules).
+		SetTimeout(timeout).
+		EnableTracing(cov == nil).
+		CapturePrintOutput(true).
+		Filter(run)
+
+	if cov != nil {
+		runner.SetCoverageQueryTracer(cov)
+	}
+
+	txn, err := store.NewTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer store.Abort(ctx, txn)
+
+	ch, err := runner.RunTests(ctx, txn)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*tester.Result
+	for result := range ch {
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func testStatus(result *tester.Result) string {
+	if result.Pass() {
+		return testStatusPass
+	} else if result.Skip {
+		return testStatusSkip
+	} else if result.Error != nil {
+		return testStatusError
+	}
+	return testStatusFail
+}
+
+func testResults(results []*tester.Result) ([]*pb.TestResult, bool) {
+	var res []*pb.TestResult
+	success := true
+
+	for _, result := range results {
+		element := &pb.TestResult{
+			Package:    result.Package,
+			Name:       result.Name,
+			Status:     testStatus(result),
+			DurationNs: result.Duration.Nanoseconds(),
+			Output:     string(result.Output),
+		}
+		if result.Location != nil {
+			element.File = result.Location.File
+			element.Row = int32(result.Location.Row)
+		}
+		if result.Error != nil {
+			element.Error = result.Error.Error()
+		}
+		if result.Fail {
+			element.Trace = myUtil.PrettyTrace(myUtil.FilterTrace(result.Trace, myUtil.ExplainFails))
+		}
+		if element.Status == testStatusFail || element.Status == testStatusError {
+			success = false
+		}
+		res = append(res, element)
+	}
+
+	return res, success
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Line      int32         `xml:"line,attr,omitempty"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// junitReport renders the results as JUnit XML with a test suite per
+// package.
+func junitReport(results []*pb.TestResult) (string, error) {
+	report := junitTestSuites{
+		Name: "opa-go-service",
+	}
+	suites := map[string]int{}
+
+	for _, result := range results {
+		index, exist := suites[result.Package]
+		if !exist {
+			index = len(report.Suites)
+			suites[result.Package] = index
+			report.Suites = append(report.Suites, junitTestSuite{
+				Name: result.Package,
+			})
+		}
+		suite := &report.Suites[index]
+
+		testCase := junitTestCase{
+			Name:      result.Name,
+			ClassName: result.Package,
+			File:      result.File,
+			Line:      result.Row,
+			Time:      fmt.Sprintf("%.6f", time.Duration(result.DurationNs).Seconds()),
+			SystemOut: result.Output,
+		}
+		switch result.Status {
+		case testStatusFail:
+			testCase.Failure = &junitMessage{Message: "test failed", Text: result.Trace}
+			suite.Failures++
+			report.Failures++
+		case testStatusError:
+			testCase.Error = &junitMessage{Message: result.Error}
+			suite.Errors++
+			report.Errors++
+		case testStatusSkip:
+			testCase.Skipped = &junitMessage{}
+			suite.Skipped++
+		}
+		suite.Tests++
+		report.Tests++
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	for index := range report.Suites {
+		var total time.Duration
+		for _, result := range results {
+			if result.Package == report.Suites[index].Name {
+				total += time.Duration(result.DurationNs)
+			}
+		}
+		report.Suites[index].Time = fmt.Sprintf("%.6f", total.Seconds())
+	}
+
+	bs, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(bs) + "\n", nil
+}
+
+func RunTestsRego(ctx context.Context, in *pb.RunTestsRequest) (*pb.RunTestsResult, error) {
+	if len(in.Packages) == 0 {
+		return &pb.RunTestsResult{
+			IsSuccess: false,
+			Error:     "Need packages",
+		}, nil
+	}
+
+	timeout := defaultTestTimeout
+	if in.Timeout != "" {
+		var err error
+		timeout, err = time.ParseDuration(in.Timeout)
+		if err != nil {
+			return &pb.RunTestsResult{
+				IsSuccess: false,
+				Error:     fmt.Sprintf("unable to parse timeout: %v", err),
+			}, nil
+		}
+	}
+	if timeout <= 0 || timeout > serverMaxTestTimeout {
+		timeout = serverMaxTestTimeout
+	}
+
+	modules := map[string]*ast.Module{}
+	for index, data := range in.Packages {
+		name := fmt.Sprintf("rego_%d.rego", index)
+		module, err := ast.ParseModuleWithOpts(name, data, ast.ParserOptions{ProcessAnnotation: true})
+		if err != nil {
+			return &pb.RunTestsResult{
+				IsSuccess: false,
+				Error:     fmt.Sprintf("unable to parse module: %v", err),
+			}, nil
+		}
+		modules[name] = module
+	}
+
+	store := inmem.New()
+	if in.Data != "" {
+		var data map[string]interface{}
+		err := util.Unmarshal([]byte(in.Data), &data)
+		if err != nil {
+			return &pb.RunTestsResult{
+				IsSuccess: false,
+				Error:     fmt.Sprintf("unable to parse data: %v", err),
+			}, nil
+		}
+		store = inmem.NewFromObject(data)
+	}
+
+	results, err := runRegoTests(ctx, modules, store, in.Run, timeout, nil)
+	if err != nil {
+		return &pb.RunTestsResult{
+			IsSuccess: false,
+			Error:     fmt.Sprintf("Unable Run Tests: %v", err),
+		}, nil
+	}
+
+	res := &pb.RunTestsResult{}
+	res.Results, res.IsSuccess = testResults(results)
+
+	if in.Junit {
+		res.Junit, err = junitReport(res.Results)
+		if err != nil {
+			return &pb.RunTestsResult{
+				IsSuccess: false,
+				Error:     fmt.Sprintf("Unable JUnit: %v", err),
+			}, nil
+		}
+	}
+
+	return res, nil
+}
+
+func (s *server) RunTests(ctx context.Context, in *pb.RunTestsRequest) (*pb.RunTestsResult, error) {
+	return RunTestsRego(ctx, in)
+}
+
+func RunTests(c echo.Context) error {
+	data := new(pb.RunTestsRequest)
+	err := c.Bind(data)
+	if err != nil {
+		c.JSON(http.StatusOK, &pb.RunTestsResult{
+			IsSuccess: false,
+			Error:     fmt.Sprintf("Unable Post Data: %v", err),
+		})
+		return nil
+	}
+	res, err := RunTestsRego(c.Request().Context(), data)
+
+	if err != nil {
+		c.JSON(http.StatusOK, &pb.RunTestsResult{
+			IsSuccess: false,
+			Error:     fmt.Sprintf("Unable Run Tests: %v", err),
+		})
+		return nil
+	}
+
+	c.JSON(http.StatusOK, res)
+	return nil
+}
+
+func init() {
+
+	params := testCommandParams{
+		format: util.NewEnumFlag(evalFormatPretty, []string{evalFormatPretty, evalFormatJSON, testFormatJUnit}),
+	}
+
+	testCommand := &cobra.Command{
+		Use:   "test <path> [path [...]]",
+		Short: "Execute Rego test cases",
+		Long: `Execute Rego test cases.
+
+The test command runs the rules prefixed with "test_" in the policy and
+data files found under the paths. The exit code is non-zero when a test
+fails or errors.
+
+With --coverage the coverage report is printed as JSON instead of the test
+results, and --threshold fails the command when the coverage percentage is
+lower.
+
+Examples
+--------
+
+    $ opa-go-service test policy/
+
+    $ opa-go-service test --format junit --run 'test_allow.*' policy/ data.json
+`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("specify at least one file")
+			}
+			if params.threshold > 0 && !params.coverage {
+				return errors.New("specify --threshold with --coverage")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			ok, err := test(args, params, os.Stdout)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			if !ok {
+				os.Exit(1)
+			}
+		},
+	}
+
+	testCommand.Flags().StringVarP(&params.run, "run", "r", "", "run only test cases matching the regular expression")
+	testCommand.Flags().DurationVarP(&params.timeout, "timeout", "t", defaultTestTimeout, "set test timeout")
+	testCommand.Flags().BoolVarP(&params.verbose, "verbose", "v", false, "set verbose reporting mode")
+	addOutputFormatFlag(testCommand.Flags(), params.format)
+	addCoverageFlag(testCommand.Flags(), &params.coverage)
+	testCommand.Flags().Float64VarP(&params.threshold, "threshold", "", 0, "set coverage threshold and exit with non-zero status if coverage is less than threshold %")
+	RootCommand.AddCommand(testCommand)
+}
+
+func test(args []string, params testCommandParams, w io.Writer) (bool, error) {
+	ctx := context.Background()
+
+	f := loaderFilter{}
+	modules, store, err := tester.Load(args, f.Apply)
+	if err != nil {
+		return false, err
+	}
+
+	var cov *cover.Cover
+	if params.coverage {
+		cov = cover.New()
+	}
+
+	results, err := runRegoTests(ctx, modules, store, params.run, params.timeout, cov)
+	if err != nil {
+		return false, err
+	}
+
+	res, success := testResults(results)
+
+	if cov != nil {
+		report := cov.Report(modules)
+		if err := printJSON(w, report); err != nil {
+			return false, err
+		}
+		if report.Coverage < params.threshold {
+			fmt.Fprintf(os.Stderr, "Code coverage threshold not met: got %.2f instead of %.2f\n", report.Coverage, params.threshold)
+			return false, nil
+		}
+		return success, nil
+	}
+
+	switch params.format.String() {
+	case evalFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(&pb.RunTestsResult{
+			IsSuccess: success,
+			Results:   res,
+		})
+	case testFormatJUnit:
+		var report string
+		report, err = junitReport(res)
+		if err == nil {
+			_, err = io.WriteString(w, report)
+		}
+	default:
+		ch := make(chan *tester.Result, len(results))
+		for _, result := range results {
+			ch <- result
+		}
+		close(ch)
+		err = tester.PrettyReporter{
+			Output:  w,
+			Verbose: params.verbose,
+		}.Report(ch)
+	}
+
+	return success, err
+}
@@ -10,13 +10,15 @@ import (
 	"github.com/spf13/cobra"
 )
 
+const version = "v0.0.4"
+
 func init() {
 	versionCommand := &cobra.Command{
 		Use:   "version",
 		Short: "version",
 		Long:  `Version`,
 		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println("v0.0.4")
+			fmt.Println(version)
 		},
 	}
 	RootCommand.AddCommand(versionCommand)
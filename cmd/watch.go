@@ -0,0 +1,176 @@
+// Copyright 2023 Honyrik.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/open-policy-agent/opa/loader"
+)
+
+const (
+	clearScreen     = "\033[H\033[2J"
+	watchTimeFormat = "15:04:05"
+
+	// watchDebounce groups the events of one save, editors write a file in
+	// several steps.
+	watchDebounce = 100 * time.Millisecond
+)
+
+// evalWatcher tracks the data and bundle paths, the input and the schema
+// files. Files are watched through their directory so the rename of an atomic
+// save is seen, the directories are watched with all of their subdirectories.
+type evalWatcher struct {
+	watcher *fsnotify.Watcher
+	files   map[string]bool
+	dirs    []string
+}
+
+func newEvalWatcher(params evalCommandParams) (*evalWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	ew := &evalWatcher{
+		watcher: watcher,
+		files:   map[string]bool{},
+	}
+
+	paths := []string{}
+	for _, path := range params.dataPaths.v {
+		_, path = loader.SplitPrefix(path)
+		paths = append(paths, strings.TrimPrefix(path, "file://"))
+	}
+	paths = append(paths, params.bundlePaths.v...)
+	for _, path := range []string{params.inputPath, params.schemaPath} {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+
+	for _, path := range paths {
+		path, err := filepath.Abs(path)
+		if err != nil {
+			watcher.Close()
+			return nil, err
+		}
+		info, err := os.Stat(path)
+		if err == nil && info.IsDir() {
+			ew.dirs = append(ew.dirs, path)
+			err = ew.addDir(path)
+		} else {
+			ew.files[path] = true
+			err = watcher.Add(filepath.Dir(path))
+		}
+		if err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+
+	return ew, nil
+}
+
+// addDir watches dir and its subdirectories.
+func (ew *evalWatcher) addDir(dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return ew.watcher.Add(path)
+		}
+		return nil
+	})
+}
+
+// changed reports whether the event touches a watched path, new directories
+// under a data directory are watched from then on.
+func (ew *evalWatcher) changed(event fsnotify.Event) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+	if ew.files[event.Name] {
+		return true
+	}
+	for _, dir := range ew.dirs {
+		if event.Name == dir || strings.HasPrefix(event.Name, dir+string(filepath.Separator)) {
+			if event.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					ew.addDir(event.Name)
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+func (ew *evalWatcher) Close() error {
+	return ew.watcher.Close()
+}
+
+// watchEval evaluates the query on every change of the data paths or the
+// input file. The screen shows the last result with its timing, a failed
+// evaluation shows its error under the last good result.
+func watchEval(args []string, params evalCommandParams, w io.Writer) error {
+	ew, err := newEvalWatcher(params)
+	if err != nil {
+		return err
+	}
+	defer ew.Close()
+
+	var lastGood []byte
+	run := func() {
+		var buf bytes.Buffer
+		start := time.Now()
+		_, err := eval(args, params, &buf, &buf)
+		elapsed := time.Since(start)
+
+		fmt.Fprint(w, clearScreen)
+		if err == nil {
+			lastGood = buf.Bytes()
+			w.Write(lastGood)
+			fmt.Fprintf(w, "\nEvaluated in %v at %s\n", elapsed, start.Format(watchTimeFormat))
+		} else {
+			if lastGood != nil {
+				w.Write(lastGood)
+				fmt.Fprintln(w, "\n(last good result)")
+			}
+			fmt.Fprintf(w, "\nError at %s:\n%v\n", start.Format(watchTimeFormat), err)
+		}
+		fmt.Fprintln(w, "\nWatching for changes...")
+	}
+
+	run()
+
+	timer := time.NewTimer(0)
+	<-timer.C
+	for {
+		select {
+		case event, ok := <-ew.watcher.Events:
+			if !ok {
+				return nil
+			}
+			if ew.changed(event) {
+				timer.Reset(watchDebounce)
+			}
+		case err, ok := <-ew.watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return err
+		case <-timer.C:
+			run()
+		}
+	}
+}
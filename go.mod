@@ -3,12 +3,20 @@ module github.com/Honyrik/opa-go-service
 go 1.16
 
 require (
+	github.com/BurntSushi/toml v1.2.1
 	github.com/fatih/structs v1.1.0
+	github.com/fsnotify/fsnotify v1.6.0
+	github.com/ghodss/yaml v1.0.0
+	github.com/jmespath/go-jmespath v0.4.0
 	github.com/open-policy-agent/opa v0.49.0
+	github.com/peterh/liner v0.0.0-20170211195444-bf27d3ba8e1d
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/spf13/cobra v1.6.1
 	github.com/spf13/pflag v1.0.5
+	github.com/tmccombs/hcl2json v0.3.4
 	google.golang.org/grpc v1.52.3
 	google.golang.org/protobuf v1.28.1
+	gopkg.in/yaml.v2 v2.4.0
 	k8s.io/client-go v0.26.1
 )
 
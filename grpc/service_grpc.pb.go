@@ -23,6 +23,10 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ApiClient interface {
 	Execute(ctx context.Context, in *ApiRequest, opts ...grpc.CallOption) (*ApiResult, error)
+	Compile(ctx context.Context, in *CompileRequest, opts ...grpc.CallOption) (*CompileResult, error)
+	Check(ctx context.Context, in *CheckRequest, opts ...grpc.CallOption) (*CheckResult, error)
+	Format(ctx context.Context, in *FormatRequest, opts ...grpc.CallOption) (*FormatResult, error)
+	RunTests(ctx context.Context, in *RunTestsRequest, opts ...grpc.CallOption) (*RunTestsResult, error)
 }
 
 type apiClient struct {
@@ -42,11 +46,51 @@ func (c *apiClient) Execute(ctx context.Context, in *ApiRequest, opts ...grpc.Ca
 	return out, nil
 }
 
+func (c *apiClient) Compile(ctx context.Context, in *CompileRequest, opts ...grpc.CallOption) (*CompileResult, error) {
+	out := new(CompileResult)
+	err := c.cc.Invoke(ctx, "/OPA.Api/Compile", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) Check(ctx context.Context, in *CheckRequest, opts ...grpc.CallOption) (*CheckResult, error) {
+	out := new(CheckResult)
+	err := c.cc.Invoke(ctx, "/OPA.Api/Check", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) Format(ctx context.Context, in *FormatRequest, opts ...grpc.CallOption) (*FormatResult, error) {
+	out := new(FormatResult)
+	err := c.cc.Invoke(ctx, "/OPA.Api/Format", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *apiClient) RunTests(ctx context.Context, in *RunTestsRequest, opts ...grpc.CallOption) (*RunTestsResult, error) {
+	out := new(RunTestsResult)
+	err := c.cc.Invoke(ctx, "/OPA.Api/RunTests", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ApiServer is the server API for Api service.
 // All implementations must embed UnimplementedApiServer
 // for forward compatibility
 type ApiServer interface {
 	Execute(context.Context, *ApiRequest) (*ApiResult, error)
+	Compile(context.Context, *CompileRequest) (*CompileResult, error)
+	Check(context.Context, *CheckRequest) (*CheckResult, error)
+	Format(context.Context, *FormatRequest) (*FormatResult, error)
+	RunTests(context.Context, *RunTestsRequest) (*RunTestsResult, error)
 	mustEmbedUnimplementedApiServer()
 }
 
@@ -57,6 +101,18 @@ type UnimplementedApiServer struct {
 func (UnimplementedApiServer) Execute(context.Context, *ApiRequest) (*ApiResult, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Execute not implemented")
 }
+func (UnimplementedApiServer) Compile(context.Context, *CompileRequest) (*CompileResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Compile not implemented")
+}
+func (UnimplementedApiServer) Check(context.Context, *CheckRequest) (*CheckResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Check not implemented")
+}
+func (UnimplementedApiServer) Format(context.Context, *FormatRequest) (*FormatResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Format not implemented")
+}
+func (UnimplementedApiServer) RunTests(context.Context, *RunTestsRequest) (*RunTestsResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RunTests not implemented")
+}
 func (UnimplementedApiServer) mustEmbedUnimplementedApiServer() {}
 
 // UnsafeApiServer may be embedded to opt out of forward compatibility for this service.
@@ -88,6 +144,78 @@ func _Api_Execute_Handler(srv interface{}, ctx context.Context, dec func(interfa
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Api_Compile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).Compile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/OPA.Api/Compile",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).Compile(ctx, req.(*CompileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_Check_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).Check(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/OPA.Api/Check",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).Check(ctx, req.(*CheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_Format_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FormatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).Format(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/OPA.Api/Format",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).Format(ctx, req.(*FormatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Api_RunTests_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RunTestsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ApiServer).RunTests(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/OPA.Api/RunTests",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ApiServer).RunTests(ctx, req.(*RunTestsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Api_ServiceDesc is the grpc.ServiceDesc for Api service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -99,6 +227,22 @@ var Api_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Execute",
 			Handler:    _Api_Execute_Handler,
 		},
+		{
+			MethodName: "Compile",
+			Handler:    _Api_Compile_Handler,
+		},
+		{
+			MethodName: "Check",
+			Handler:    _Api_Check_Handler,
+		},
+		{
+			MethodName: "Format",
+			Handler:    _Api_Format_Handler,
+		},
+		{
+			MethodName: "RunTests",
+			Handler:    _Api_RunTests_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "service.proto",
@@ -0,0 +1,175 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ghodss/yaml"
+	opaUtil "github.com/open-policy-agent/opa/util"
+	"github.com/tmccombs/hcl2json/convert"
+	yamlv2 "gopkg.in/yaml.v2"
+)
+
+const (
+	InputFormatJSON = "json"
+	InputFormatYAML = "yaml"
+	InputFormatTOML = "toml"
+	InputFormatHCL2 = "hcl2"
+)
+
+var InputFormats = []string{InputFormatJSON, InputFormatYAML, InputFormatTOML, InputFormatHCL2}
+
+var inputFormatExtensions = map[string]string{
+	".json": InputFormatJSON,
+	".yaml": InputFormatYAML,
+	".yml":  InputFormatYAML,
+	".toml": InputFormatTOML,
+	".tf":   InputFormatHCL2,
+	".hcl":  InputFormatHCL2,
+}
+
+func ValidateInputFormat(format string) error {
+	switch format {
+	case "", InputFormatJSON, InputFormatYAML, InputFormatTOML, InputFormatHCL2:
+		return nil
+	}
+	return fmt.Errorf("invalid input format %q, expected one of: %s", format, strings.Join(InputFormats, ", "))
+}
+
+// InputFormatFromPath returns the input format of the file extension, empty
+// when the extension is unknown.
+func InputFormatFromPath(path string) string {
+	return inputFormatExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// ParseInput parses the input document of format, name is the file name in
+// the errors. The errors carry the line of the document. An empty format
+// accepts JSON or YAML. YAML with several documents is parsed as the array of
+// the documents, HCL2 is converted like hcl2json does for Terraform files.
+func ParseInput(name string, bs []byte, format string) (interface{}, error) {
+	var input interface{}
+	switch format {
+	case "":
+		if err := opaUtil.Unmarshal(bs, &input); err != nil {
+			return nil, err
+		}
+		return input, nil
+	case InputFormatJSON:
+		return parseJSONInput(name, bs)
+	case InputFormatYAML:
+		return parseYAMLInput(name, bs)
+	case InputFormatTOML:
+		var doc map[string]interface{}
+		if _, err := toml.Decode(string(bs), &doc); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		return roundTripInput(name, doc)
+	case InputFormatHCL2:
+		js, err := convert.Bytes(bs, name, convert.Options{})
+		if err != nil {
+			return nil, err
+		}
+		return parseJSONInput(name, js)
+	}
+	return nil, ValidateInputFormat(format)
+}
+
+// parseJSONInput decodes one JSON document with the numbers as json.Number,
+// the errors report the line and column of the offset.
+func parseJSONInput(name string, bs []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(bs))
+	decoder.UseNumber()
+
+	var input interface{}
+	if err := decoder.Decode(&input); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line, col := inputPosition(bs, syntaxErr.Offset-1)
+			return nil, fmt.Errorf("%s:%d:%d: %v", name, line, col, err)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			line, col := inputPosition(bs, int64(len(bs)))
+			return nil, fmt.Errorf("%s:%d:%d: unexpected end of JSON input", name, line, col)
+		}
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	rest := bs[decoder.InputOffset():]
+	if trimmed := bytes.TrimLeft(rest, " \t\r\n"); len(trimmed) > 0 {
+		line, col := inputPosition(bs, decoder.InputOffset()+int64(len(rest)-len(trimmed)))
+		return nil, fmt.Errorf("%s:%d:%d: unexpected data after the JSON document", name, line, col)
+	}
+	return input, nil
+}
+
+// parseYAMLInput decodes the YAML documents of bs, empty documents are
+// skipped. The parser errors report the line in the stream.
+func parseYAMLInput(name string, bs []byte) (interface{}, error) {
+	decoder := yamlv2.NewDecoder(bytes.NewReader(bs))
+
+	var docs []interface{}
+	for {
+		var doc interface{}
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		if doc == nil {
+			continue
+		}
+		docBytes, err := yamlv2.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		js, err := yaml.YAMLToJSON(docBytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		input, err := parseJSONInput(name, js)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, input)
+	}
+
+	switch len(docs) {
+	case 0:
+		return nil, nil
+	case 1:
+		return docs[0], nil
+	}
+	return docs, nil
+}
+
+// roundTripInput converts the decoded document to JSON values, such as the
+// dates of TOML to strings.
+func roundTripInput(name string, doc interface{}) (interface{}, error) {
+	bs, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return parseJSONInput(name, bs)
+}
+
+// inputPosition returns the line and the column of the byte at offset, from
+// one.
+func inputPosition(bs []byte, offset int64) (int, int) {
+	if offset > int64(len(bs)) {
+		offset = int64(len(bs))
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	before := bs[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := int(offset) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
@@ -0,0 +1,62 @@
+package util
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseInput(t *testing.T) {
+	tests := []struct {
+		note     string
+		format   string
+		input    string
+		expected string
+	}{
+		{note: "json", format: InputFormatJSON, input: `{"a": 1}`, expected: `{"a":1}`},
+		{note: "yaml", format: InputFormatYAML, input: "a: 1\n", expected: `{"a":1}`},
+		{note: "yaml documents", format: InputFormatYAML, input: "a: 1\n---\nb: 2\n", expected: `[{"a":1},{"b":2}]`},
+		{note: "toml", format: InputFormatTOML, input: "a = 1\n", expected: `{"a":1}`},
+		{note: "hcl2", format: InputFormatHCL2, input: "a = 1\n", expected: `{"a":1}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			input, err := ParseInput("input", []byte(tc.input), tc.format)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			bs, err := json.Marshal(input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(bs) != tc.expected {
+				t.Fatalf("expected %s but got %s", tc.expected, bs)
+			}
+		})
+	}
+}
+
+func TestParseInputErrors(t *testing.T) {
+	tests := []struct {
+		note     string
+		format   string
+		input    string
+		expected string
+	}{
+		{note: "json", format: InputFormatJSON, input: "{\n\"a\": 1,\n}", expected: "input:3:1"},
+		{note: "yaml", format: InputFormatYAML, input: "a: 1\nb: 2\nc: [\n", expected: "line 3"},
+		{note: "toml", format: InputFormatTOML, input: "a = 1\nb = 2\nc = = 3\n", expected: "line 3"},
+		{note: "hcl2", format: InputFormatHCL2, input: "a = 1\nb = 2\nc = = 3\n", expected: "input:3"},
+		{note: "invalid format", format: "xml", input: "<a/>", expected: `invalid input format "xml"`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			_, err := ParseInput("input", []byte(tc.input), tc.format)
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Fatalf("expected an error with %s but got %v", tc.expected, err)
+			}
+		})
+	}
+}
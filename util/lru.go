@@ -0,0 +1,99 @@
+package util
+
+import (
+	"container/list"
+	"sync"
+)
+
+// LRU is a cache of at most size entries, the least recently used entry is
+// evicted when a new one is added to a full cache. It is safe for concurrent
+// use.
+type LRU struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	value interface{}
+}
+
+func NewLRU(size int) *LRU {
+	if size < 1 {
+		size = 1
+	}
+	return &LRU{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Get returns the value of key and marks it as the most recently used.
+func (c *LRU) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, exist := c.items[key]
+	if !exist {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry).value, true
+}
+
+// Add sets the value of key, evicting the least recently used entry when the
+// cache is full.
+func (c *LRU) Add(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.add(key, value)
+}
+
+// GetOrAdd returns the value of key, or adds the value returned by fn when the
+// key is missing.
+func (c *LRU) GetOrAdd(key string, fn func() interface{}) interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, exist := c.items[key]; exist {
+		c.order.MoveToFront(elem)
+		return elem.Value.(*lruEntry).value
+	}
+	value := fn()
+	c.add(key, value)
+	return value
+}
+
+// Purge removes all the entries.
+func (c *LRU) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *LRU) add(key string, value interface{}) {
+	if elem, exist := c.items[key]; exist {
+		elem.Value.(*lruEntry).value = value
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, value: value})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+	}
+}
@@ -0,0 +1,120 @@
+package util
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/profiler"
+)
+
+var ProfileSortCriteria = []string{"total_time_ns", "num_eval", "num_redo", "file", "line"}
+
+var profileCompare = map[string]func(p1, p2 *profiler.ExprStats) int{
+	"total_time_ns": func(p1, p2 *profiler.ExprStats) int {
+		return compareInt64(p2.ExprTimeNs, p1.ExprTimeNs)
+	},
+	"num_eval": func(p1, p2 *profiler.ExprStats) int {
+		return compareInt64(int64(p2.NumEval), int64(p1.NumEval))
+	},
+	"num_redo": func(p1, p2 *profiler.ExprStats) int {
+		return compareInt64(int64(p2.NumRedo), int64(p1.NumRedo))
+	},
+	"file": func(p1, p2 *profiler.ExprStats) int {
+		return strings.Compare(statLocation(p1).File, statLocation(p2).File)
+	},
+	"line": func(p1, p2 *profiler.ExprStats) int {
+		return compareInt64(int64(statLocation(p1).Row), int64(statLocation(p2).Row))
+	},
+}
+
+func statLocation(p *profiler.ExprStats) *ast.Location {
+	if p.Location == nil {
+		return &ast.Location{}
+	}
+	return p.Location
+}
+
+func compareInt64(a, b int64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+func ValidateProfileSort(criteria []string) error {
+	for _, c := range criteria {
+		if _, ok := profileCompare[c]; !ok {
+			return fmt.Errorf("invalid profile sort criteria %q, expected one of: %s", c, strings.Join(ProfileSortCriteria, ", "))
+		}
+	}
+	return nil
+}
+
+// SortProfile orders the stats by the criteria (ProfileSortCriteria when
+// empty) and keeps the first limit entries. A limit <= 0 keeps all.
+func SortProfile(stats []profiler.ExprStats, criteria []string, limit int) []profiler.ExprStats {
+	if len(criteria) == 0 {
+		criteria = ProfileSortCriteria
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		for _, c := range criteria {
+			if compare, ok := profileCompare[c]; ok {
+				if r := compare(&stats[i], &stats[j]); r != 0 {
+					return r < 0
+				}
+			}
+		}
+		return false
+	})
+	if limit > 0 && limit < len(stats) {
+		return stats[:limit]
+	}
+	return stats
+}
+
+// ProfileAggregator sums expression stats over many evaluations.
+type ProfileAggregator struct {
+	mu    sync.Mutex
+	stats map[string]*profiler.ExprStats
+}
+
+func NewProfileAggregator() *ProfileAggregator {
+	return &ProfileAggregator{
+		stats: make(map[string]*profiler.ExprStats),
+	}
+}
+
+func (a *ProfileAggregator) Add(stats []profiler.ExprStats) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, stat := range stats {
+		loc := statLocation(&stat)
+		key := fmt.Sprintf("%s:%d:%d", loc.File, loc.Row, loc.Col)
+		current, exist := a.stats[key]
+		if !exist {
+			value := stat
+			a.stats[key] = &value
+			continue
+		}
+		current.ExprTimeNs += stat.ExprTimeNs
+		current.NumEval += stat.NumEval
+		current.NumRedo += stat.NumRedo
+	}
+}
+
+func (a *ProfileAggregator) Stats() []profiler.ExprStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	res := make([]profiler.ExprStats, 0, len(a.stats))
+	for _, stat := range a.stats {
+		res = append(res, *stat)
+	}
+	return res
+}
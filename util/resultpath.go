@@ -0,0 +1,118 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jmespath/go-jmespath"
+	"github.com/open-policy-agent/opa/rego"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+const (
+	ResultPathJSONPath = "jsonpath"
+	ResultPathJMESPath = "jmespath"
+	ResultPathRego     = "rego"
+)
+
+var ResultPathLanguages = []string{ResultPathJSONPath, ResultPathJMESPath, ResultPathRego}
+
+// maxResultPathRego bounds the prepared rego result paths, the least recently
+// used are prepared again.
+const maxResultPathRego = 100
+
+var cacheResultPathRego = NewLRU(maxResultPathRego)
+
+func ValidateResultPathLanguage(language string) error {
+	switch language {
+	case "", ResultPathJSONPath, ResultPathJMESPath, ResultPathRego:
+		return nil
+	}
+	return fmt.Errorf("invalid result path language %q, expected one of: %s", language, strings.Join(ResultPathLanguages, ", "))
+}
+
+// ExecuteResultPath projects res with path and writes the projection to w.
+// The default language is a Kubernetes jsonpath template printed as text.
+// A jmespath expression, or a rego query evaluated with res as input, is
+// printed as JSON; for rego the value of the first expression is used.
+func ExecuteResultPath(ctx context.Context, w io.Writer, res interface{}, path string, language string) error {
+	switch language {
+	case "", ResultPathJSONPath:
+		parse := jsonpath.New("")
+		parse.EnableJSONOutput(false)
+		resultPathErr := parse.Parse(path)
+		if resultPathErr != nil {
+			return fmt.Errorf("Unable Prepare Result Path: %v", resultPathErr)
+		}
+		printErr := parse.Execute(w, res)
+		if printErr != nil {
+			return fmt.Errorf("Unable Find Result Path: %v", printErr)
+		}
+		return nil
+	case ResultPathJMESPath:
+		value, err := roundTripJSON(res)
+		if err != nil {
+			return err
+		}
+		parse, resultPathErr := jmespath.Compile(path)
+		if resultPathErr != nil {
+			return fmt.Errorf("Unable Prepare Result Path: %v", resultPathErr)
+		}
+		out, searchErr := parse.Search(value)
+		if searchErr != nil {
+			return fmt.Errorf("Unable Find Result Path: %v", searchErr)
+		}
+		return writeJSON(w, out)
+	case ResultPathRego:
+		value, err := roundTripJSON(res)
+		if err != nil {
+			return err
+		}
+		pq, resultPathErr := prepareResultPathRego(ctx, path)
+		if resultPathErr != nil {
+			return fmt.Errorf("Unable Prepare Result Path: %v", resultPathErr)
+		}
+		result, evalErr := pq.Eval(ctx, rego.EvalInput(value))
+		if evalErr != nil {
+			return fmt.Errorf("Unable Find Result Path: %v", evalErr)
+		}
+		return writeJSON(w, ResultSetFirst(result))
+	}
+	return ValidateResultPathLanguage(language)
+}
+
+func prepareResultPathRego(ctx context.Context, path string) (rego.PreparedEvalQuery, error) {
+	if pq, exist := cacheResultPathRego.Get(path); exist {
+		return pq.(rego.PreparedEvalQuery), nil
+	}
+	pq, err := rego.New(rego.Query(path)).PrepareForEval(ctx)
+	if err != nil {
+		return pq, err
+	}
+	cacheResultPathRego.Add(path, pq)
+	return pq, nil
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("Unable Json: %v", err)
+	}
+	_, err = w.Write(bs)
+	return err
+}
+
+func roundTripJSON(res interface{}) (interface{}, error) {
+	bs, err := json.Marshal(res)
+	if err != nil {
+		return nil, fmt.Errorf("Unable Json: %v", err)
+	}
+	var value interface{}
+	if err := json.Unmarshal(bs, &value); err != nil {
+		return nil, fmt.Errorf("Unable Json: %v", err)
+	}
+	return value, nil
+}
@@ -0,0 +1,296 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+const (
+	SQLDialectPostgres = "postgres"
+	SQLDialectSQLite   = "sqlite"
+)
+
+var SQLDialects = []string{SQLDialectPostgres, SQLDialectSQLite}
+
+var sqlIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+var sqlOperators = map[string]string{
+	ast.Equality.Name:      "=",
+	ast.Equal.Name:         "=",
+	ast.NotEqual.Name:      "<>",
+	ast.LessThan.Name:      "<",
+	ast.LessThanEq.Name:    "<=",
+	ast.GreaterThan.Name:   ">",
+	ast.GreaterThanEq.Name: ">=",
+	ast.Member.Name:        "IN",
+}
+
+// ResidualToSQL translates the residual queries of a partial evaluation with
+// unknowns over data.tables.<table>.<column> into a WHERE clause with
+// dialect specific placeholders. Queries are joined with OR and expressions
+// of a query with AND.
+//
+// Only comparisons between a column and a scalar (or another column),
+// membership of a column in an array or set of scalars and negations of
+// these are supported. A null scalar compares with IS NULL, inequality and
+// the equality of two columns are null-safe and ordering comparisons never
+// match NULL columns. Anything else is rejected with an error instead of
+// producing an inexact filter.
+func ResidualToSQL(pq *rego.PartialQueries, dialect string) (string, []interface{}, error) {
+	t := &sqlTranslator{}
+	switch dialect {
+	case SQLDialectPostgres, "postgresql":
+		t.dialect = SQLDialectPostgres
+	case SQLDialectSQLite:
+		t.dialect = SQLDialectSQLite
+	default:
+		return "", nil, fmt.Errorf("invalid sql dialect %q, expected one of: %s", dialect, strings.Join(SQLDialects, ", "))
+	}
+
+	if len(pq.Support) > 0 {
+		return "", nil, fmt.Errorf("unsupported residual: support modules cannot be translated to sql")
+	}
+
+	if len(pq.Queries) == 0 {
+		return "1 = 0", nil, nil
+	}
+
+	var clauses []string
+	for _, query := range pq.Queries {
+		if len(query) == 0 {
+			return "1 = 1", nil, nil
+		}
+		var exprs []string
+		for _, expr := range query {
+			clause, err := t.expr(expr)
+			if err != nil {
+				return "", nil, fmt.Errorf("unsupported expression %v: %v", expr, err)
+			}
+			exprs = append(exprs, clause)
+		}
+		clauses = append(clauses, strings.Join(exprs, " AND "))
+	}
+
+	if len(clauses) == 1 {
+		return clauses[0], t.args, nil
+	}
+
+	return "(" + strings.Join(clauses, ") OR (") + ")", t.args, nil
+}
+
+type sqlTranslator struct {
+	dialect string
+	args    []interface{}
+}
+
+func (t *sqlTranslator) placeholder(value interface{}) string {
+	t.args = append(t.args, value)
+	if t.dialect == SQLDialectPostgres {
+		return fmt.Sprintf("$%d", len(t.args))
+	}
+	return "?"
+}
+
+func (t *sqlTranslator) expr(expr *ast.Expr) (string, error) {
+	if len(expr.With) > 0 {
+		return "", fmt.Errorf("with modifiers are not supported")
+	}
+
+	if !expr.IsCall() {
+		return "", fmt.Errorf("only comparisons are supported")
+	}
+
+	name := expr.Operator().String()
+	op, ok := sqlOperators[name]
+	if !ok {
+		return "", fmt.Errorf("function %s is not supported", name)
+	}
+
+	operands := expr.Operands()
+	if len(operands) != 2 {
+		return "", fmt.Errorf("function %s expects 2 operands", name)
+	}
+
+	var clause string
+	var err error
+	if op == "IN" {
+		clause, err = t.member(operands[0], operands[1])
+	} else {
+		clause, err = t.compare(op, operands[0], operands[1])
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if expr.Negated {
+		return fmt.Sprintf("NOT COALESCE(%s, FALSE)", clause), nil
+	}
+	return clause, nil
+}
+
+var sqlFlipOperators = map[string]string{
+	"=":  "=",
+	"<>": "<>",
+	"<":  ">",
+	"<=": ">=",
+	">":  "<",
+	">=": "<=",
+}
+
+func (t *sqlTranslator) compare(op string, a, b *ast.Term) (string, error) {
+	left, leftIsColumn, err := column(a)
+	if err != nil {
+		return "", err
+	}
+	right, rightIsColumn, err := column(b)
+	if err != nil {
+		return "", err
+	}
+
+	if leftIsColumn && rightIsColumn {
+		switch op {
+		case "=":
+			return t.notDistinct(left, right), nil
+		case "<>":
+			return t.distinct(left, right), nil
+		}
+		return fmt.Sprintf("%s %s %s", left, op, right), nil
+	}
+
+	if !leftIsColumn && !rightIsColumn {
+		return "", fmt.Errorf("expected a data.tables.<table>.<column> operand")
+	}
+
+	if !leftIsColumn {
+		op = sqlFlipOperators[op]
+		left, b = right, a
+	}
+
+	value, err := scalar(b)
+	if err != nil {
+		return "", err
+	}
+
+	if value == nil {
+		switch op {
+		case "=":
+			return fmt.Sprintf("%s IS NULL", left), nil
+		case "<>":
+			return fmt.Sprintf("%s IS NOT NULL", left), nil
+		}
+		return "", fmt.Errorf("null can only be compared for equality")
+	}
+
+	if op == "<>" {
+		return t.distinct(left, t.placeholder(value)), nil
+	}
+
+	return fmt.Sprintf("%s %s %s", left, op, t.placeholder(value)), nil
+}
+
+// notDistinct is the null-safe equality, two NULL columns are equal like
+// two null values in Rego.
+func (t *sqlTranslator) notDistinct(left, right string) string {
+	if t.dialect == SQLDialectPostgres {
+		return fmt.Sprintf("%s IS NOT DISTINCT FROM %s", left, right)
+	}
+	return fmt.Sprintf("%s IS %s", left, right)
+}
+
+func (t *sqlTranslator) distinct(left, right string) string {
+	if t.dialect == SQLDialectPostgres {
+		return fmt.Sprintf("%s IS DISTINCT FROM %s", left, right)
+	}
+	return fmt.Sprintf("%s IS NOT %s", left, right)
+}
+
+func (t *sqlTranslator) member(a, b *ast.Term) (string, error) {
+	left, isColumn, err := column(a)
+	if err != nil {
+		return "", err
+	}
+	if !isColumn {
+		return "", fmt.Errorf("expected a data.tables.<table>.<column> member")
+	}
+
+	var elements []*ast.Term
+	switch collection := b.Value.(type) {
+	case *ast.Array:
+		collection.Foreach(func(x *ast.Term) {
+			elements = append(elements, x)
+		})
+	case ast.Set:
+		collection.Sorted().Foreach(func(x *ast.Term) {
+			elements = append(elements, x)
+		})
+	default:
+		return "", fmt.Errorf("expected an array or set of scalars")
+	}
+
+	if len(elements) == 0 {
+		return "1 = 0", nil
+	}
+
+	var placeholders []string
+	for _, element := range elements {
+		value, err := scalar(element)
+		if err != nil {
+			return "", err
+		}
+		if value == nil {
+			return "", fmt.Errorf("null is not supported in collections")
+		}
+		placeholders = append(placeholders, t.placeholder(value))
+	}
+
+	return fmt.Sprintf("%s IN (%s)", left, strings.Join(placeholders, ", ")), nil
+}
+
+// column returns the quoted "table"."column" for refs of the form
+// data.tables.<table>.<column>.
+func column(term *ast.Term) (string, bool, error) {
+	ref, ok := term.Value.(ast.Ref)
+	if !ok {
+		return "", false, nil
+	}
+
+	if len(ref) != 4 || !ref[0].Equal(ast.DefaultRootDocument) || !ref[1].Equal(ast.StringTerm("tables")) {
+		return "", false, fmt.Errorf("reference %v is not of the form data.tables.<table>.<column>", ref)
+	}
+
+	var parts []string
+	for _, part := range ref[2:] {
+		name, ok := part.Value.(ast.String)
+		if !ok || !sqlIdentifier.MatchString(string(name)) {
+			return "", false, fmt.Errorf("reference %v has an invalid table or column name", ref)
+		}
+		parts = append(parts, fmt.Sprintf("%q", string(name)))
+	}
+
+	return strings.Join(parts, "."), true, nil
+}
+
+func scalar(term *ast.Term) (interface{}, error) {
+	switch value := term.Value.(type) {
+	case ast.Null:
+		return nil, nil
+	case ast.Boolean:
+		return bool(value), nil
+	case ast.String:
+		return string(value), nil
+	case ast.Number:
+		if i, ok := value.Int64(); ok {
+			return i, nil
+		}
+		if f, err := json.Number(value).Float64(); err == nil {
+			return f, nil
+		}
+		return nil, fmt.Errorf("number %v is out of range", value)
+	}
+	return nil, fmt.Errorf("expected a scalar value, got %v", ast.TypeName(term.Value))
+}
@@ -0,0 +1,210 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+func TestResidualToSQL(t *testing.T) {
+	tests := []struct {
+		note     string
+		queries  []string
+		dialect  string
+		expected string
+		args     []interface{}
+	}{
+		{
+			note:     "eq postgres",
+			queries:  []string{`data.tables.posts.author = "bob"`},
+			dialect:  SQLDialectPostgres,
+			expected: `"posts"."author" = $1`,
+			args:     []interface{}{"bob"},
+		},
+		{
+			note:     "eq sqlite",
+			queries:  []string{`data.tables.posts.author = "bob"`},
+			dialect:  SQLDialectSQLite,
+			expected: `"posts"."author" = ?`,
+			args:     []interface{}{"bob"},
+		},
+		{
+			note:     "neq postgres",
+			queries:  []string{`data.tables.posts.author != "bob"`},
+			dialect:  SQLDialectPostgres,
+			expected: `"posts"."author" IS DISTINCT FROM $1`,
+			args:     []interface{}{"bob"},
+		},
+		{
+			note:     "neq sqlite",
+			queries:  []string{`data.tables.posts.author != "bob"`},
+			dialect:  SQLDialectSQLite,
+			expected: `"posts"."author" IS NOT ?`,
+			args:     []interface{}{"bob"},
+		},
+		{
+			note:     "neq columns postgres",
+			queries:  []string{`data.tables.posts.author != data.tables.posts.editor`},
+			dialect:  SQLDialectPostgres,
+			expected: `"posts"."author" IS DISTINCT FROM "posts"."editor"`,
+		},
+		{
+			note:     "neq columns sqlite",
+			queries:  []string{`data.tables.posts.author != data.tables.posts.editor`},
+			dialect:  SQLDialectSQLite,
+			expected: `"posts"."author" IS NOT "posts"."editor"`,
+		},
+		{
+			note:     "eq columns postgres",
+			queries:  []string{`data.tables.posts.author = data.tables.posts.editor`},
+			dialect:  SQLDialectPostgres,
+			expected: `"posts"."author" IS NOT DISTINCT FROM "posts"."editor"`,
+		},
+		{
+			note:     "eq columns sqlite",
+			queries:  []string{`data.tables.posts.author == data.tables.posts.editor`},
+			dialect:  SQLDialectSQLite,
+			expected: `"posts"."author" IS "posts"."editor"`,
+		},
+		{
+			note:     "lt columns",
+			queries:  []string{`data.tables.posts.created < data.tables.posts.updated`},
+			dialect:  SQLDialectSQLite,
+			expected: `"posts"."created" < "posts"."updated"`,
+		},
+		{
+			note:     "eq null postgres",
+			queries:  []string{`data.tables.posts.author = null`},
+			dialect:  SQLDialectPostgres,
+			expected: `"posts"."author" IS NULL`,
+		},
+		{
+			note:     "neq null sqlite",
+			queries:  []string{`data.tables.posts.author != null`},
+			dialect:  SQLDialectSQLite,
+			expected: `"posts"."author" IS NOT NULL`,
+		},
+		{
+			note:     "in postgres",
+			queries:  []string{`internal.member_2(data.tables.posts.id, [1, 2])`},
+			dialect:  SQLDialectPostgres,
+			expected: `"posts"."id" IN ($1, $2)`,
+			args:     []interface{}{int64(1), int64(2)},
+		},
+		{
+			note:     "in sqlite",
+			queries:  []string{`internal.member_2(data.tables.posts.id, {"b", "a"})`},
+			dialect:  SQLDialectSQLite,
+			expected: `"posts"."id" IN (?, ?)`,
+			args:     []interface{}{"a", "b"},
+		},
+		{
+			note:     "in empty",
+			queries:  []string{`internal.member_2(data.tables.posts.id, [])`},
+			dialect:  SQLDialectPostgres,
+			expected: `1 = 0`,
+		},
+		{
+			note:     "flipped lt postgres",
+			queries:  []string{`10 < data.tables.posts.views`},
+			dialect:  SQLDialectPostgres,
+			expected: `"posts"."views" > $1`,
+			args:     []interface{}{int64(10)},
+		},
+		{
+			note:     "flipped gte sqlite",
+			queries:  []string{`10 >= data.tables.posts.views`},
+			dialect:  SQLDialectSQLite,
+			expected: `"posts"."views" <= ?`,
+			args:     []interface{}{int64(10)},
+		},
+		{
+			note:     "flipped neq postgres",
+			queries:  []string{`"bob" != data.tables.posts.author`},
+			dialect:  SQLDialectPostgres,
+			expected: `"posts"."author" IS DISTINCT FROM $1`,
+			args:     []interface{}{"bob"},
+		},
+		{
+			note:     "flipped null sqlite",
+			queries:  []string{`null = data.tables.posts.author`},
+			dialect:  SQLDialectSQLite,
+			expected: `"posts"."author" IS NULL`,
+		},
+		{
+			note:     "negated",
+			queries:  []string{`not data.tables.posts.author = "bob"`},
+			dialect:  SQLDialectPostgres,
+			expected: `NOT COALESCE("posts"."author" = $1, FALSE)`,
+			args:     []interface{}{"bob"},
+		},
+		{
+			note:     "and or",
+			queries:  []string{`data.tables.posts.public = true`, `data.tables.posts.author = "bob"; data.tables.posts.draft = false`},
+			dialect:  SQLDialectSQLite,
+			expected: `("posts"."public" = ?) OR ("posts"."author" = ? AND "posts"."draft" = ?)`,
+			args:     []interface{}{true, "bob", false},
+		},
+		{
+			note:     "no queries",
+			dialect:  SQLDialectPostgres,
+			expected: `1 = 0`,
+		},
+		{
+			note:     "empty query",
+			queries:  []string{""},
+			dialect:  SQLDialectPostgres,
+			expected: `1 = 1`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			pq := &rego.PartialQueries{}
+			for _, query := range tc.queries {
+				body := ast.NewBody()
+				if query != "" {
+					body = ast.MustParseBody(query)
+				}
+				pq.Queries = append(pq.Queries, body)
+			}
+
+			sql, args, err := ResidualToSQL(pq, tc.dialect)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sql != tc.expected {
+				t.Errorf("expected sql %s but got %s", tc.expected, sql)
+			}
+			if !reflect.DeepEqual(args, tc.args) {
+				t.Errorf("expected args %v but got %v", tc.args, args)
+			}
+		})
+	}
+}
+
+func TestResidualToSQLErrors(t *testing.T) {
+	tests := []struct {
+		note    string
+		query   string
+		dialect string
+	}{
+		{note: "invalid dialect", query: `data.tables.posts.author = "bob"`, dialect: "mysql"},
+		{note: "null ordering", query: `data.tables.posts.author < null`, dialect: SQLDialectPostgres},
+		{note: "null in collection", query: `internal.member_2(data.tables.posts.id, [1, null])`, dialect: SQLDialectSQLite},
+		{note: "no column", query: `data.users.name = "bob"`, dialect: SQLDialectPostgres},
+		{note: "invalid column name", query: `data.tables.posts["a b"] = "bob"`, dialect: SQLDialectPostgres},
+		{note: "unsupported function", query: `startswith(data.tables.posts.author, "b")`, dialect: SQLDialectSQLite},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			pq := &rego.PartialQueries{Queries: []ast.Body{ast.MustParseBody(tc.query)}}
+			if _, _, err := ResidualToSQL(pq, tc.dialect); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/topdown"
+	"github.com/open-policy-agent/opa/topdown/lineage"
+)
+
+const (
+	ExplainOff   = "off"
+	ExplainNotes = "notes"
+	ExplainFails = "fails"
+	ExplainFull  = "full"
+)
+
+var ExplainModes = []string{ExplainOff, ExplainNotes, ExplainFails, ExplainFull}
+
+// IsExplain reports whether the explain mode requires a tracer.
+// An empty mode is treated as "off".
+func IsExplain(mode string) bool {
+	return mode != "" && mode != ExplainOff
+}
+
+func ValidateExplain(mode string) error {
+	if mode == "" {
+		return nil
+	}
+	for _, m := range ExplainModes {
+		if m == mode {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid explain mode %q, expected one of: %s", mode, strings.Join(ExplainModes, ", "))
+}
+
+func FilterTrace(trace []*topdown.Event, mode string) []*topdown.Event {
+	switch mode {
+	case ExplainNotes:
+		return lineage.Notes(trace)
+	case ExplainFails:
+		return lineage.Fails(trace)
+	case ExplainFull:
+		return lineage.Full(trace)
+	}
+	return nil
+}
+
+// PrettyTrace renders the trace as indented text with rule locations.
+func PrettyTrace(trace []*topdown.Event) string {
+	w := new(strings.Builder)
+	topdown.PrettyTraceWithLocation(w, trace)
+	return w.String()
+}
+
+type TraceLocation struct {
+	File string `json:"file,omitempty"`
+	Row  int    `json:"row"`
+	Col  int    `json:"col"`
+}
+
+type TraceEvent struct {
+	Op       string                 `json:"op"`
+	QueryID  uint64                 `json:"queryId"`
+	ParentID uint64                 `json:"parentId"`
+	Type     string                 `json:"type"`
+	Node     string                 `json:"node"`
+	Location *TraceLocation         `json:"location,omitempty"`
+	Message  string                 `json:"message,omitempty"`
+	Locals   map[string]interface{} `json:"locals,omitempty"`
+}
+
+func NewTraceEvents(trace []*topdown.Event) []TraceEvent {
+	res := make([]TraceEvent, 0, len(trace))
+
+	for _, evt := range trace {
+		event := TraceEvent{
+			Op:       strings.ToLower(string(evt.Op)),
+			QueryID:  evt.QueryID,
+			ParentID: evt.ParentID,
+			Message:  evt.Message,
+		}
+
+		switch node := evt.Node.(type) {
+		case ast.Body:
+			event.Type = "body"
+			event.Node = node.String()
+		case *ast.Expr:
+			event.Type = "expr"
+			event.Node = node.String()
+		case *ast.Rule:
+			event.Type = "rule"
+			event.Node = node.Head.String()
+		}
+
+		if evt.Location != nil {
+			event.Location = &TraceLocation{
+				File: evt.Location.File,
+				Row:  evt.Location.Row,
+				Col:  evt.Location.Col,
+			}
+		}
+
+		if evt.Locals != nil {
+			event.Locals = map[string]interface{}{}
+			evt.Locals.Iter(func(k, v ast.Value) bool {
+				name := k.String()
+				if meta, ok := evt.LocalMetadata[ast.Var(name)]; ok {
+					name = string(meta.Name)
+				}
+				if value, err := ast.JSON(v); err == nil {
+					event.Locals[name] = value
+				}
+				return false
+			})
+		}
+
+		res = append(res, event)
+	}
+
+	return res
+}
+
+// TraceJSON renders the trace as an array of TraceEvent.
+func TraceJSON(trace []*topdown.Event) (string, error) {
+	resJson, err := json.Marshal(NewTraceEvents(trace))
+	if err != nil {
+		return "", err
+	}
+	return string(resJson[:]), nil
+}
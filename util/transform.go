@@ -1,6 +1,9 @@
 package util
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/fatih/structs"
 	"github.com/open-policy-agent/opa/rego"
 )
@@ -17,3 +20,108 @@ func ResultSetTArrayMap(result rego.ResultSet) []map[string]interface{} {
 
 	return res
 }
+
+func ResultSetValues(result rego.ResultSet) [][]interface{} {
+	res := [][]interface{}{}
+
+	for _, elementResult := range result {
+		var values []interface{}
+		for _, expression := range elementResult.Expressions {
+			values = append(values, expression.Value)
+		}
+		res = append(res, values)
+	}
+
+	return res
+}
+
+func ResultSetBindings(result rego.ResultSet) []map[string]interface{} {
+	res := []map[string]interface{}{}
+
+	for _, elementResult := range result {
+		res = append(res, elementResult.Bindings)
+	}
+
+	return res
+}
+
+const (
+	ResultFormatLegacy   = ""
+	ResultFormatFull     = "full"
+	ResultFormatBindings = "bindings"
+	ResultFormatValues   = "values"
+	ResultFormatFirst    = "first"
+)
+
+var ResultFormats = []string{ResultFormatFull, ResultFormatBindings, ResultFormatValues, ResultFormatFirst}
+
+type LocationV2 struct {
+	Row int `json:"row"`
+	Col int `json:"col"`
+}
+
+type ExpressionV2 struct {
+	Value    interface{} `json:"value"`
+	Text     string      `json:"text"`
+	Location *LocationV2 `json:"location,omitempty"`
+}
+
+type ResultV2 struct {
+	Expressions []ExpressionV2         `json:"expressions"`
+	Bindings    map[string]interface{} `json:"bindings"`
+}
+
+func ResultSetFull(result rego.ResultSet) []ResultV2 {
+	res := []ResultV2{}
+
+	for _, elementResult := range result {
+		element := ResultV2{
+			Expressions: []ExpressionV2{},
+			Bindings:    map[string]interface{}{},
+		}
+		for _, expression := range elementResult.Expressions {
+			value := ExpressionV2{
+				Value: expression.Value,
+				Text:  expression.Text,
+			}
+			if expression.Location != nil {
+				value.Location = &LocationV2{
+					Row: expression.Location.Row,
+					Col: expression.Location.Col,
+				}
+			}
+			element.Expressions = append(element.Expressions, value)
+		}
+		for name, value := range elementResult.Bindings {
+			element.Bindings[name] = value
+		}
+		res = append(res, element)
+	}
+
+	return res
+}
+
+func ResultSetFirst(result rego.ResultSet) interface{} {
+	if len(result) < 1 || len(result[0].Expressions) < 1 {
+		return nil
+	}
+	return result[0].Expressions[0].Value
+}
+
+// ResultSetFormat converts the result set into the shape selected by
+// format, see ResultFormats.
+func ResultSetFormat(result rego.ResultSet, format string) (interface{}, error) {
+	switch format {
+	case ResultFormatLegacy:
+		return ResultSetTArrayMap(result), nil
+	case ResultFormatFull:
+		return ResultSetFull(result), nil
+	case ResultFormatBindings:
+		return ResultSetBindings(result), nil
+	case ResultFormatValues:
+		return ResultSetValues(result), nil
+	case ResultFormatFirst:
+		return ResultSetFirst(result), nil
+	}
+	return nil, fmt.Errorf("invalid result format %q, expected one of: %s", format, strings.Join(ResultFormats, ", "))
+}